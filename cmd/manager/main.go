@@ -17,6 +17,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -91,6 +92,30 @@ func main() {
 		"The address for health checking.",
 	)
 
+	enableLoadBalancerAvailabilityZones := flag.Bool(
+		"enable-load-balancer-availability-zones",
+		false,
+		"Enable the availability zone of a machine on the classic load balancers it is registered with, when the zone is not enabled yet.",
+	)
+
+	skipUnauthorizedTagging := flag.Bool(
+		"skip-unauthorized-tagging",
+		false,
+		"Keep reconciling machines whose instances cannot be tagged because they belong to another account, e.g. in a VPC shared through RAM.",
+	)
+
+	protectInstancesFromScaleIn := flag.Bool(
+		"protect-instances-from-scale-in",
+		false,
+		"Protect instances that are also attached to an Auto Scaling group from scale in, so that the group does not terminate them.",
+	)
+
+	instanceTagLabelPrefixes := flag.String(
+		"instance-tag-label-prefixes",
+		"",
+		"Comma separated list of label key prefixes. The machine labels matching any of them are mirrored as tags of the machine's instance, e.g. for cost allocation.",
+	)
+
 	klog.InitFlags(nil)
 	flag.Set("logtostderr", "true")
 	flag.Parse()
@@ -156,6 +181,11 @@ func main() {
 		EventRecorder:       mgr.GetEventRecorderFor("awscontroller"),
 		AwsClientBuilder:    awsclient.NewValidatedClient,
 		ConfigManagedClient: configManagedClient,
+
+		EnableLoadBalancerAvailabilityZones: *enableLoadBalancerAvailabilityZones,
+		SkipUnauthorizedTagging:             *skipUnauthorizedTagging,
+		ProtectInstancesFromScaleIn:         *protectInstancesFromScaleIn,
+		InstanceTagLabelPrefixes:            splitFlagList(*instanceTagLabelPrefixes),
 	})
 
 	if err := machine.AddWithActuator(mgr, machineActuator); err != nil {
@@ -187,6 +217,17 @@ func main() {
 	}
 }
 
+// splitFlagList splits a comma separated flag value, ignoring empty items.
+func splitFlagList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // newConfigManagedClient returns a controller-runtime client that can be used to access the openshift-config-managed
 // namespace.
 func newConfigManagedClient(mgr manager.Manager) (runtimeclient.Client, manager.Runnable, error) {
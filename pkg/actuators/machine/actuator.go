@@ -43,6 +43,11 @@ type Actuator struct {
 	eventRecorder       record.EventRecorder
 	awsClientBuilder    awsclient.AwsClientBuilderFuncType
 	configManagedClient runtimeclient.Client
+
+	enableLoadBalancerAvailabilityZones bool
+	skipUnauthorizedTagging             bool
+	protectInstancesFromScaleIn         bool
+	instanceTagLabelPrefixes            []string
 }
 
 // ActuatorParams holds parameter information for Actuator.
@@ -51,6 +56,17 @@ type ActuatorParams struct {
 	EventRecorder       record.EventRecorder
 	AwsClientBuilder    awsclient.AwsClientBuilderFuncType
 	ConfigManagedClient runtimeclient.Client
+	// EnableLoadBalancerAvailabilityZones enables the availability zone of the instance
+	// on classic load balancers that do not have it enabled before registering the instance.
+	EnableLoadBalancerAvailabilityZones bool
+	// SkipUnauthorizedTagging lets machines reconcile when their instance cannot be tagged because
+	// it belongs to another account, e.g. a participant account of a VPC shared through RAM.
+	SkipUnauthorizedTagging bool
+	// ProtectInstancesFromScaleIn protects instances that are also attached to an Auto Scaling group
+	// from scale in, so that the group does not terminate instances backing machines.
+	ProtectInstancesFromScaleIn bool
+	// InstanceTagLabelPrefixes are the label key prefixes of the machine labels mirrored as instance tags.
+	InstanceTagLabelPrefixes []string
 }
 
 // NewActuator returns an actuator.
@@ -60,6 +76,11 @@ func NewActuator(params ActuatorParams) *Actuator {
 		eventRecorder:       params.EventRecorder,
 		awsClientBuilder:    params.AwsClientBuilder,
 		configManagedClient: params.ConfigManagedClient,
+
+		enableLoadBalancerAvailabilityZones: params.EnableLoadBalancerAvailabilityZones,
+		skipUnauthorizedTagging:             params.SkipUnauthorizedTagging,
+		protectInstancesFromScaleIn:         params.ProtectInstancesFromScaleIn,
+		instanceTagLabelPrefixes:            params.InstanceTagLabelPrefixes,
 	}
 }
 
@@ -82,6 +103,11 @@ func (a *Actuator) Create(ctx context.Context, machine *machinev1.Machine) error
 		machine:             machine,
 		awsClientBuilder:    a.awsClientBuilder,
 		configManagedClient: a.configManagedClient,
+
+		enableLoadBalancerAvailabilityZones: a.enableLoadBalancerAvailabilityZones,
+		skipUnauthorizedTagging:             a.skipUnauthorizedTagging,
+		protectInstancesFromScaleIn:         a.protectInstancesFromScaleIn,
+		instanceTagLabelPrefixes:            a.instanceTagLabelPrefixes,
 	})
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
@@ -108,6 +134,11 @@ func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool
 		machine:             machine,
 		awsClientBuilder:    a.awsClientBuilder,
 		configManagedClient: a.configManagedClient,
+
+		enableLoadBalancerAvailabilityZones: a.enableLoadBalancerAvailabilityZones,
+		skipUnauthorizedTagging:             a.skipUnauthorizedTagging,
+		protectInstancesFromScaleIn:         a.protectInstancesFromScaleIn,
+		instanceTagLabelPrefixes:            a.instanceTagLabelPrefixes,
 	})
 	if err != nil {
 		return false, fmt.Errorf(scopeFailFmt, machine.GetName(), err)
@@ -124,6 +155,11 @@ func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) error
 		machine:             machine,
 		awsClientBuilder:    a.awsClientBuilder,
 		configManagedClient: a.configManagedClient,
+
+		enableLoadBalancerAvailabilityZones: a.enableLoadBalancerAvailabilityZones,
+		skipUnauthorizedTagging:             a.skipUnauthorizedTagging,
+		protectInstancesFromScaleIn:         a.protectInstancesFromScaleIn,
+		instanceTagLabelPrefixes:            a.instanceTagLabelPrefixes,
 	})
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
@@ -163,6 +199,11 @@ func (a *Actuator) Delete(ctx context.Context, machine *machinev1.Machine) error
 		machine:             machine,
 		awsClientBuilder:    a.awsClientBuilder,
 		configManagedClient: a.configManagedClient,
+
+		enableLoadBalancerAvailabilityZones: a.enableLoadBalancerAvailabilityZones,
+		skipUnauthorizedTagging:             a.skipUnauthorizedTagging,
+		protectInstancesFromScaleIn:         a.protectInstancesFromScaleIn,
+		instanceTagLabelPrefixes:            a.instanceTagLabelPrefixes,
 	})
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
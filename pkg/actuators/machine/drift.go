@@ -0,0 +1,83 @@
+package machine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+// instanceDrift compares the instance with the provider spec it was created from and returns
+// a human readable description for each material difference, in the form "<field> <actual> != <desired>".
+// Only fields which can be compared without querying AWS are considered, so security groups
+// and subnets referenced by filters are ignored.
+func instanceDrift(instance *ec2.Instance, spec *machinev1.AWSMachineProviderConfig) []string {
+	drift := []string{}
+	if instance == nil || spec == nil {
+		return drift
+	}
+
+	if actual := aws.StringValue(instance.InstanceType); spec.InstanceType != "" && actual != spec.InstanceType {
+		drift = append(drift, fmt.Sprintf("instanceType %s != %s", actual, spec.InstanceType))
+	}
+
+	if spec.Subnet.ID != nil {
+		if actual := aws.StringValue(instance.SubnetId); actual != *spec.Subnet.ID {
+			drift = append(drift, fmt.Sprintf("subnet %s != %s", actual, *spec.Subnet.ID))
+		}
+	}
+
+	if desired, ok := securityGroupIDsFromSpec(spec.SecurityGroups); ok {
+		actual := []string{}
+		for _, group := range instance.SecurityGroups {
+			actual = append(actual, aws.StringValue(group.GroupId))
+		}
+		sort.Strings(actual)
+		if strings.Join(actual, ",") != strings.Join(desired, ",") {
+			drift = append(drift, fmt.Sprintf("securityGroups [%s] != [%s]", strings.Join(actual, ","), strings.Join(desired, ",")))
+		}
+	}
+
+	if spec.IAMInstanceProfile != nil && spec.IAMInstanceProfile.ID != nil {
+		actual := ""
+		if instance.IamInstanceProfile != nil {
+			actual = instanceProfileNameFromARN(aws.StringValue(instance.IamInstanceProfile.Arn))
+		}
+		if actual != *spec.IAMInstanceProfile.ID {
+			drift = append(drift, fmt.Sprintf("iamInstanceProfile %s != %s", actual, *spec.IAMInstanceProfile.ID))
+		}
+	}
+
+	return drift
+}
+
+// securityGroupIDsFromSpec returns the sorted security group IDs referenced in the provider spec.
+// It returns false if any of the groups is referenced by filters, as the full set can then
+// only be determined by querying AWS.
+func securityGroupIDsFromSpec(securityGroups []machinev1.AWSResourceReference) ([]string, bool) {
+	if len(securityGroups) == 0 {
+		return nil, false
+	}
+
+	ids := []string{}
+	for _, group := range securityGroups {
+		if group.ID == nil {
+			return nil, false
+		}
+		ids = append(ids, *group.ID)
+	}
+	sort.Strings(ids)
+	return ids, true
+}
+
+// instanceProfileNameFromARN returns the instance profile name from an ARN of the form
+// arn:aws:iam::<account>:instance-profile/<path>/<name>.
+func instanceProfileNameFromARN(arn string) string {
+	if i := strings.LastIndex(arn, "/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
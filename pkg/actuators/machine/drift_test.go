@@ -0,0 +1,135 @@
+package machine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+)
+
+func TestInstanceDrift(t *testing.T) {
+	instance := func() *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId:   aws.String("i-1"),
+			InstanceType: aws.String("m5.large"),
+			SubnetId:     aws.String("subnet-1"),
+			SecurityGroups: []*ec2.GroupIdentifier{
+				{GroupId: aws.String("sg-2")},
+				{GroupId: aws.String("sg-1")},
+			},
+			IamInstanceProfile: &ec2.IamInstanceProfile{
+				Arn: aws.String("arn:aws:iam::123456789012:instance-profile/path/worker-profile"),
+			},
+		}
+	}
+	spec := func() *machinev1.AWSMachineProviderConfig {
+		return &machinev1.AWSMachineProviderConfig{
+			InstanceType: "m5.large",
+			Subnet:       machinev1.AWSResourceReference{ID: aws.String("subnet-1")},
+			SecurityGroups: []machinev1.AWSResourceReference{
+				{ID: aws.String("sg-1")},
+				{ID: aws.String("sg-2")},
+			},
+			IAMInstanceProfile: &machinev1.AWSResourceReference{ID: aws.String("worker-profile")},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		instance      func() *ec2.Instance
+		spec          func() *machinev1.AWSMachineProviderConfig
+		expectedDrift []string
+	}{
+		{
+			name:          "No drift",
+			instance:      instance,
+			spec:          spec,
+			expectedDrift: []string{},
+		},
+		{
+			name:     "Instance type drift",
+			instance: instance,
+			spec: func() *machinev1.AWSMachineProviderConfig {
+				s := spec()
+				s.InstanceType = "m5.xlarge"
+				return s
+			},
+			expectedDrift: []string{"instanceType m5.large != m5.xlarge"},
+		},
+		{
+			name:     "Subnet drift",
+			instance: instance,
+			spec: func() *machinev1.AWSMachineProviderConfig {
+				s := spec()
+				s.Subnet.ID = aws.String("subnet-2")
+				return s
+			},
+			expectedDrift: []string{"subnet subnet-1 != subnet-2"},
+		},
+		{
+			name:     "Subnet filters are ignored",
+			instance: instance,
+			spec: func() *machinev1.AWSMachineProviderConfig {
+				s := spec()
+				s.Subnet = machinev1.AWSResourceReference{Filters: []machinev1.Filter{{Name: "tag:Name", Values: []string{"private"}}}}
+				return s
+			},
+			expectedDrift: []string{},
+		},
+		{
+			name:     "Security group drift",
+			instance: instance,
+			spec: func() *machinev1.AWSMachineProviderConfig {
+				s := spec()
+				s.SecurityGroups = []machinev1.AWSResourceReference{{ID: aws.String("sg-1")}, {ID: aws.String("sg-3")}}
+				return s
+			},
+			expectedDrift: []string{"securityGroups [sg-1,sg-2] != [sg-1,sg-3]"},
+		},
+		{
+			name:     "Security group filters are ignored",
+			instance: instance,
+			spec: func() *machinev1.AWSMachineProviderConfig {
+				s := spec()
+				s.SecurityGroups = []machinev1.AWSResourceReference{{ID: aws.String("sg-1")}, {Filters: []machinev1.Filter{{Name: "tag:Name", Values: []string{"workers"}}}}}
+				return s
+			},
+			expectedDrift: []string{},
+		},
+		{
+			name: "IAM instance profile drift",
+			instance: func() *ec2.Instance {
+				i := instance()
+				i.IamInstanceProfile = nil
+				return i
+			},
+			spec:          spec,
+			expectedDrift: []string{"iamInstanceProfile  != worker-profile"},
+		},
+		{
+			name: "Multiple drifts",
+			instance: func() *ec2.Instance {
+				i := instance()
+				i.InstanceType = aws.String("m5.2xlarge")
+				i.SubnetId = aws.String("subnet-3")
+				return i
+			},
+			spec: spec,
+			expectedDrift: []string{
+				"instanceType m5.2xlarge != m5.large",
+				"subnet subnet-3 != subnet-1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			drift := instanceDrift(tc.instance(), tc.spec())
+			if !reflect.DeepEqual(drift, tc.expectedDrift) {
+				t.Errorf("expected: %q, got: %q", tc.expectedDrift, drift)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/iam"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
+)
+
+const (
+	// InstanceProfilePermissionsValid reports whether the role of the instance profile is allowed the actions
+	// required by the cloud provider integration. The check is advisory and does not block the machine.
+	InstanceProfilePermissionsValid machinev1.ConditionType = "InstanceProfilePermissionsValid"
+
+	// InstanceProfilePermissionsAllowedConditionReason is the condition reason when all required actions are allowed
+	InstanceProfilePermissionsAllowedConditionReason = "InstanceProfilePermissionsAllowed"
+	// InstanceProfilePermissionsDeniedConditionReason is the condition reason when any required action is denied
+	InstanceProfilePermissionsDeniedConditionReason = "InstanceProfilePermissionsDenied"
+)
+
+// validateInstanceProfilePermissions simulates the policies of the role of the named instance profile
+// against the required actions and returns the actions that are not allowed, sorted.
+func validateInstanceProfilePermissions(client awsclient.Client, profileName string, requiredActions []string) ([]string, error) {
+	profile, err := client.GetInstanceProfile(&iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(profileName),
+	})
+	if err != nil {
+		klog.Errorf("Error getting instance profile %s: %v", profileName, err)
+		return nil, fmt.Errorf("error getting instance profile %s: %v", profileName, err)
+	}
+	if profile.InstanceProfile == nil || len(profile.InstanceProfile.Roles) == 0 {
+		return nil, fmt.Errorf("instance profile %s has no role", profileName)
+	}
+	roleARN := profile.InstanceProfile.Roles[0].Arn
+
+	denied := []string{}
+	input := &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: roleARN,
+		ActionNames:     aws.StringSlice(requiredActions),
+	}
+	for {
+		result, err := client.SimulatePrincipalPolicy(input)
+		if err != nil {
+			klog.Errorf("Error simulating policies of role %s: %v", aws.StringValue(roleARN), err)
+			return nil, fmt.Errorf("error simulating policies of role %s: %v", aws.StringValue(roleARN), err)
+		}
+		for _, evaluation := range result.EvaluationResults {
+			if aws.StringValue(evaluation.EvalDecision) != iam.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, aws.StringValue(evaluation.EvalActionName))
+			}
+		}
+		if !aws.BoolValue(result.IsTruncated) {
+			break
+		}
+		input.Marker = result.Marker
+	}
+
+	sort.Strings(denied)
+	return denied, nil
+}
+
+// instanceProfilePermissionsCondition returns the condition reporting the actions denied to the instance profile.
+func instanceProfilePermissionsCondition(deniedActions []string) machinev1.AWSMachineProviderCondition {
+	if len(deniedActions) == 0 {
+		return machinev1.AWSMachineProviderCondition{
+			Type:    InstanceProfilePermissionsValid,
+			Status:  corev1.ConditionTrue,
+			Reason:  InstanceProfilePermissionsAllowedConditionReason,
+			Message: "Instance profile is allowed all required actions",
+		}
+	}
+	return machinev1.AWSMachineProviderCondition{
+		Type:    InstanceProfilePermissionsValid,
+		Status:  corev1.ConditionFalse,
+		Reason:  InstanceProfilePermissionsDeniedConditionReason,
+		Message: fmt.Sprintf("Instance profile is denied required actions: %s", strings.Join(deniedActions, ", ")),
+	}
+}
+
+// policyStringList is a policy element that holds either a single string or a list of strings.
+type policyStringList []string
+
+func (l *policyStringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = []string{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// policyPrincipal is the principal element of a policy statement: either "*" or a map of
+// principal types, e.g. AWS, Service or Federated, to the principals of that type.
+type policyPrincipal map[string]policyStringList
+
+func (p *policyPrincipal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		*p = policyPrincipal{"*": {wildcard}}
+		return nil
+	}
+	principals := map[string]policyStringList{}
+	if err := json.Unmarshal(data, &principals); err != nil {
+		return err
+	}
+	*p = principals
+	return nil
+}
+
+type trustPolicyStatement struct {
+	Effect    string           `json:"Effect"`
+	Action    policyStringList `json:"Action"`
+	Principal policyPrincipal  `json:"Principal"`
+}
+
+type trustPolicyDocument struct {
+	Statement []trustPolicyStatement `json:"Statement"`
+}
+
+// validateAssumeRolePolicy checks that the trust policy of the role allows the principal to assume it,
+// e.g. the OIDC provider of the cluster for roles assumed with web identity. The principal is matched
+// against the principals of the statements allowing an sts:AssumeRole* action, whatever their type.
+func validateAssumeRolePolicy(client awsclient.Client, roleArn, trustedPrincipal string) error {
+	parsed, err := arn.Parse(roleArn)
+	if err != nil || !strings.HasPrefix(parsed.Resource, "role/") {
+		return fmt.Errorf("invalid role ARN %q", roleArn)
+	}
+	roleName := parsed.Resource[strings.LastIndex(parsed.Resource, "/")+1:]
+
+	result, err := client.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		klog.Errorf("Error getting role %s: %v", roleArn, err)
+		return fmt.Errorf("error getting role %s: %v", roleArn, err)
+	}
+	if result.Role == nil || result.Role.AssumeRolePolicyDocument == nil {
+		return fmt.Errorf("role %s has no trust policy", roleArn)
+	}
+
+	// The policy document is returned URL encoded
+	document, err := url.QueryUnescape(aws.StringValue(result.Role.AssumeRolePolicyDocument))
+	if err != nil {
+		return fmt.Errorf("error decoding trust policy of role %s: %v", roleArn, err)
+	}
+	policy := &trustPolicyDocument{}
+	if err := json.Unmarshal([]byte(document), policy); err != nil {
+		return fmt.Errorf("error parsing trust policy of role %s: %v", roleArn, err)
+	}
+
+	for _, statement := range policy.Statement {
+		if statement.Effect != "Allow" || !allowsAssumeRole(statement.Action) {
+			continue
+		}
+		for _, principals := range statement.Principal {
+			if stringSliceContains(principals, trustedPrincipal) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("trust policy of role %s does not allow %s to assume it", roleArn, trustedPrincipal)
+}
+
+// allowsAssumeRole returns true if the actions include any of the sts:AssumeRole actions.
+func allowsAssumeRole(actions []string) bool {
+	for _, action := range actions {
+		if action == "*" || action == "sts:*" || strings.HasPrefix(action, "sts:AssumeRole") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package machine
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/golang/mock/gomock"
+	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateInstanceProfilePermissions(t *testing.T) {
+	roleARN := "arn:aws:iam::123456789012:role/worker"
+	profile := &iam.GetInstanceProfileOutput{
+		InstanceProfile: &iam.InstanceProfile{
+			InstanceProfileName: aws.String("worker-profile"),
+			Roles:               []*iam.Role{{Arn: aws.String(roleARN)}},
+		},
+	}
+	evaluation := func(action, decision string) *iam.EvaluationResult {
+		return &iam.EvaluationResult{EvalActionName: aws.String(action), EvalDecision: aws.String(decision)}
+	}
+	requiredActions := []string{"ec2:DescribeInstances", "ec2:DescribeRegions", "elasticloadbalancing:DescribeLoadBalancers"}
+
+	testCases := []struct {
+		name           string
+		expectations   func(*mockaws.MockClientMockRecorder)
+		expectedDenied []string
+		expectError    bool
+	}{
+		{
+			name: "All actions allowed",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetInstanceProfile(&iam.GetInstanceProfileInput{InstanceProfileName: aws.String("worker-profile")}).Return(profile, nil)
+				m.SimulatePrincipalPolicy(&iam.SimulatePrincipalPolicyInput{
+					PolicySourceArn: aws.String(roleARN),
+					ActionNames:     aws.StringSlice(requiredActions),
+				}).Return(&iam.SimulatePolicyResponse{
+					EvaluationResults: []*iam.EvaluationResult{
+						evaluation("ec2:DescribeInstances", iam.PolicyEvaluationDecisionTypeAllowed),
+						evaluation("ec2:DescribeRegions", iam.PolicyEvaluationDecisionTypeAllowed),
+						evaluation("elasticloadbalancing:DescribeLoadBalancers", iam.PolicyEvaluationDecisionTypeAllowed),
+					},
+				}, nil)
+			},
+			expectedDenied: []string{},
+		},
+		{
+			name: "Denied actions across pages",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetInstanceProfile(gomock.Any()).Return(profile, nil)
+				gomock.InOrder(
+					m.SimulatePrincipalPolicy(gomock.Any()).Return(&iam.SimulatePolicyResponse{
+						EvaluationResults: []*iam.EvaluationResult{
+							evaluation("ec2:DescribeInstances", iam.PolicyEvaluationDecisionTypeAllowed),
+							evaluation("ec2:DescribeRegions", iam.PolicyEvaluationDecisionTypeImplicitDeny),
+						},
+						IsTruncated: aws.Bool(true),
+						Marker:      aws.String("page-2"),
+					}, nil),
+					m.SimulatePrincipalPolicy(&iam.SimulatePrincipalPolicyInput{
+						PolicySourceArn: aws.String(roleARN),
+						ActionNames:     aws.StringSlice(requiredActions),
+						Marker:          aws.String("page-2"),
+					}).Return(&iam.SimulatePolicyResponse{
+						EvaluationResults: []*iam.EvaluationResult{
+							evaluation("elasticloadbalancing:DescribeLoadBalancers", iam.PolicyEvaluationDecisionTypeExplicitDeny),
+						},
+					}, nil),
+				)
+			},
+			expectedDenied: []string{"ec2:DescribeRegions", "elasticloadbalancing:DescribeLoadBalancers"},
+		},
+		{
+			name: "Instance profile without role",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetInstanceProfile(gomock.Any()).Return(&iam.GetInstanceProfileOutput{InstanceProfile: &iam.InstanceProfile{}}, nil)
+			},
+			expectError: true,
+		},
+		{
+			name: "Simulation error",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetInstanceProfile(gomock.Any()).Return(profile, nil)
+				m.SimulatePrincipalPolicy(gomock.Any()).Return(nil, fmt.Errorf("error"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			denied, err := validateInstanceProfilePermissions(mockAWSClient, "worker-profile", requiredActions)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if !tc.expectError && !reflect.DeepEqual(denied, tc.expectedDenied) {
+				t.Errorf("Expected denied actions %v, got %v", tc.expectedDenied, denied)
+			}
+		})
+	}
+}
+
+func TestInstanceProfilePermissionsCondition(t *testing.T) {
+	condition := instanceProfilePermissionsCondition(nil)
+	if condition.Status != corev1.ConditionTrue || condition.Reason != InstanceProfilePermissionsAllowedConditionReason {
+		t.Errorf("Expected an allowed condition, got %+v", condition)
+	}
+
+	condition = instanceProfilePermissionsCondition([]string{"ec2:DescribeRegions", "ec2:DescribeInstances"})
+	if condition.Status != corev1.ConditionFalse || condition.Reason != InstanceProfilePermissionsDeniedConditionReason {
+		t.Errorf("Expected a denied condition, got %+v", condition)
+	}
+	if expected := "Instance profile is denied required actions: ec2:DescribeRegions, ec2:DescribeInstances"; condition.Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, condition.Message)
+	}
+}
+
+func TestValidateAssumeRolePolicy(t *testing.T) {
+	roleARN := "arn:aws:iam::123456789012:role/cluster/ebs-csi-driver"
+	oidcProvider := "arn:aws:iam::123456789012:oidc-provider/oidc.example.com/cluster"
+	role := func(document string) *iam.GetRoleOutput {
+		return &iam.GetRoleOutput{Role: &iam.Role{
+			Arn:                      aws.String(roleARN),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(document)),
+		}}
+	}
+	webIdentityPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"` + oidcProvider + `"},` +
+		`"Action":"sts:AssumeRoleWithWebIdentity"}]}`
+	servicePolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com"]},` +
+		`"Action":["sts:AssumeRole"]}]}`
+	denyPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":{"Federated":"` + oidcProvider + `"},` +
+		`"Action":"sts:AssumeRoleWithWebIdentity"}]}`
+
+	testCases := []struct {
+		name             string
+		roleARN          string
+		trustedPrincipal string
+		output           *iam.GetRoleOutput
+		getRoleErr       error
+		expectError      bool
+	}{
+		{
+			name:             "OIDC provider trusted",
+			roleARN:          roleARN,
+			trustedPrincipal: oidcProvider,
+			output:           role(webIdentityPolicy),
+		},
+		{
+			name:             "Service trusted",
+			roleARN:          roleARN,
+			trustedPrincipal: "ec2.amazonaws.com",
+			output:           role(servicePolicy),
+		},
+		{
+			name:             "Principal not trusted",
+			roleARN:          roleARN,
+			trustedPrincipal: oidcProvider,
+			output:           role(servicePolicy),
+			expectError:      true,
+		},
+		{
+			name:             "Principal denied",
+			roleARN:          roleARN,
+			trustedPrincipal: oidcProvider,
+			output:           role(denyPolicy),
+			expectError:      true,
+		},
+		{
+			name:             "API error",
+			roleARN:          roleARN,
+			trustedPrincipal: oidcProvider,
+			getRoleErr:       fmt.Errorf("error"),
+			expectError:      true,
+		},
+		{
+			name:             "Invalid role ARN",
+			roleARN:          "ebs-csi-driver",
+			trustedPrincipal: oidcProvider,
+			expectError:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.output != nil || tc.getRoleErr != nil {
+				mockAWSClient.EXPECT().GetRole(&iam.GetRoleInput{RoleName: aws.String("ebs-csi-driver")}).Return(tc.output, tc.getRoleErr)
+			}
+
+			err := validateAssumeRolePolicy(mockAWSClient, tc.roleARN, tc.trustedPrincipal)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
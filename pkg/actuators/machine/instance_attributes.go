@@ -0,0 +1,404 @@
+package machine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
+	"k8s.io/klog/v2"
+)
+
+// ensureSourceDestCheck aligns the source/destination check attribute of the instance with the desired value.
+// Instances acting as routers or NAT nodes need the check disabled to forward traffic.
+func ensureSourceDestCheck(client awsclient.Client, instance *ec2.Instance, desired bool) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	attribute, err := client.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+		InstanceId: instance.InstanceId,
+		Attribute:  aws.String(ec2.InstanceAttributeNameSourceDestCheck),
+	})
+	if err != nil {
+		klog.Errorf("Error describing source/dest check of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error describing source/dest check of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	if attribute.SourceDestCheck != nil && aws.BoolValue(attribute.SourceDestCheck.Value) == desired {
+		return nil
+	}
+
+	klog.Infof("Setting source/dest check of instance %s to %t", *instance.InstanceId, desired)
+	_, err = client.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId:      instance.InstanceId,
+		SourceDestCheck: &ec2.AttributeBooleanValue{Value: aws.Bool(desired)},
+	})
+	if err != nil {
+		klog.Errorf("Error modifying source/dest check of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error modifying source/dest check of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	return nil
+}
+
+// enaUnsupportedInstanceFamilies are the previous generation instance families that do not support
+// the Elastic Network Adapter. m4.16xlarge is the only m4 size that supports it.
+var enaUnsupportedInstanceFamilies = []string{"c1", "c3", "c4", "d2", "g2", "i2", "m1", "m2", "m3", "m4", "r3", "t1", "t2"}
+
+// isENACapableInstanceType returns true if the instance type supports the Elastic Network Adapter.
+func isENACapableInstanceType(instanceType string) bool {
+	if instanceType == "m4.16xlarge" {
+		return true
+	}
+	family := strings.SplitN(instanceType, ".", 2)[0]
+	for _, unsupported := range enaUnsupportedInstanceFamilies {
+		if family == unsupported {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureENASupport enables the Elastic Network Adapter attribute on instances of ENA capable types
+// launched from AMIs that have it disabled. The attribute can only be changed while the instance is stopped.
+func ensureENASupport(client awsclient.Client, instance *ec2.Instance) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if aws.BoolValue(instance.EnaSupport) || !isENACapableInstanceType(aws.StringValue(instance.InstanceType)) {
+		return nil
+	}
+
+	state := ""
+	if instance.State != nil {
+		state = aws.StringValue(instance.State.Name)
+	}
+	if state != ec2.InstanceStateNameStopped {
+		return fmt.Errorf("instance %s must be stopped to enable ENA support, current state is %q", *instance.InstanceId, state)
+	}
+
+	klog.Infof("Enabling ENA support on instance %s", *instance.InstanceId)
+	_, err := client.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId: instance.InstanceId,
+		EnaSupport: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
+	})
+	if err != nil {
+		klog.Errorf("Error enabling ENA support on instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error enabling ENA support on instance %s: %v", *instance.InstanceId, err)
+	}
+
+	return nil
+}
+
+// primaryNetworkInterface returns the network interface attached to the instance at device index 0.
+func primaryNetworkInterface(instance *ec2.Instance) (*ec2.InstanceNetworkInterface, error) {
+	for _, networkInterface := range instance.NetworkInterfaces {
+		if networkInterface.Attachment != nil && aws.Int64Value(networkInterface.Attachment.DeviceIndex) == 0 {
+			return networkInterface, nil
+		}
+	}
+	return nil, fmt.Errorf("no primary network interface found for instance %s", aws.StringValue(instance.InstanceId))
+}
+
+// reconcilePrimaryENISecurityGroups replaces the security groups of the instance's primary network interface
+// with the desired groups when the two sets differ.
+func reconcilePrimaryENISecurityGroups(client awsclient.Client, instance *ec2.Instance, desiredGroupIDs []string) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if len(desiredGroupIDs) == 0 {
+		return fmt.Errorf("at least one security group is required for instance %s", *instance.InstanceId)
+	}
+
+	networkInterface, err := primaryNetworkInterface(instance)
+	if err != nil {
+		return err
+	}
+
+	currentGroupIDs := []string{}
+	for _, group := range networkInterface.Groups {
+		currentGroupIDs = append(currentGroupIDs, aws.StringValue(group.GroupId))
+	}
+	desired := append([]string{}, desiredGroupIDs...)
+	sort.Strings(currentGroupIDs)
+	sort.Strings(desired)
+	if strings.Join(currentGroupIDs, ",") == strings.Join(desired, ",") {
+		return nil
+	}
+
+	klog.Infof("Updating security groups of network interface %s of instance %s from %v to %v",
+		aws.StringValue(networkInterface.NetworkInterfaceId), *instance.InstanceId, currentGroupIDs, desired)
+	_, err = client.ModifyNetworkInterfaceAttribute(&ec2.ModifyNetworkInterfaceAttributeInput{
+		NetworkInterfaceId: networkInterface.NetworkInterfaceId,
+		Groups:             aws.StringSlice(desired),
+	})
+	if err != nil {
+		klog.Errorf("Error modifying security groups of network interface %s: %v", aws.StringValue(networkInterface.NetworkInterfaceId), err)
+		return fmt.Errorf("error modifying security groups of network interface %s: %v", aws.StringValue(networkInterface.NetworkInterfaceId), err)
+	}
+
+	return nil
+}
+
+// securityGroupDrift compares the security groups of the instance's primary network interface with the desired groups.
+// It returns the desired groups missing from the interface and the groups attached to the interface that are not desired,
+// both sorted. An instance without primary network interface has all the desired groups missing.
+func securityGroupDrift(instance *ec2.Instance, desiredGroupIDs []string) (missing, extra []string) {
+	current := map[string]struct{}{}
+	if instance != nil {
+		if networkInterface, err := primaryNetworkInterface(instance); err == nil {
+			for _, group := range networkInterface.Groups {
+				current[aws.StringValue(group.GroupId)] = struct{}{}
+			}
+		}
+	}
+
+	desired := map[string]struct{}{}
+	for _, groupID := range desiredGroupIDs {
+		desired[groupID] = struct{}{}
+		if _, ok := current[groupID]; !ok {
+			missing = append(missing, groupID)
+		}
+	}
+	for groupID := range current {
+		if _, ok := desired[groupID]; !ok {
+			extra = append(extra, groupID)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
+
+// reconcileIPv6AddressCount assigns or unassigns IPv6 addresses on the primary network interface
+// of the instance so that it carries the desired number of addresses.
+// When addresses need to be removed, the last ones in sorted order are unassigned.
+func reconcileIPv6AddressCount(client awsclient.Client, instance *ec2.Instance, desired int) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if desired < 1 {
+		return fmt.Errorf("desired IPv6 address count must be at least 1, got %d", desired)
+	}
+
+	networkInterface, err := primaryNetworkInterface(instance)
+	if err != nil {
+		return err
+	}
+	networkInterfaceID := aws.StringValue(networkInterface.NetworkInterfaceId)
+
+	current := []string{}
+	for _, address := range networkInterface.Ipv6Addresses {
+		current = append(current, aws.StringValue(address.Ipv6Address))
+	}
+	sort.Strings(current)
+
+	switch {
+	case len(current) < desired:
+		klog.Infof("Assigning %d IPv6 addresses to network interface %s of instance %s", desired-len(current), networkInterfaceID, *instance.InstanceId)
+		_, err := client.AssignIpv6Addresses(&ec2.AssignIpv6AddressesInput{
+			NetworkInterfaceId: networkInterface.NetworkInterfaceId,
+			Ipv6AddressCount:   aws.Int64(int64(desired - len(current))),
+		})
+		if err != nil {
+			klog.Errorf("Error assigning IPv6 addresses to network interface %s: %v", networkInterfaceID, err)
+			return fmt.Errorf("error assigning IPv6 addresses to network interface %s: %v", networkInterfaceID, err)
+		}
+	case len(current) > desired:
+		toRemove := current[desired:]
+		klog.Infof("Unassigning IPv6 addresses %v from network interface %s of instance %s", toRemove, networkInterfaceID, *instance.InstanceId)
+		_, err := client.UnassignIpv6Addresses(&ec2.UnassignIpv6AddressesInput{
+			NetworkInterfaceId: networkInterface.NetworkInterfaceId,
+			Ipv6Addresses:      aws.StringSlice(toRemove),
+		})
+		if err != nil {
+			klog.Errorf("Error unassigning IPv6 addresses from network interface %s: %v", networkInterfaceID, err)
+			return fmt.Errorf("error unassigning IPv6 addresses from network interface %s: %v", networkInterfaceID, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureVolumeDeleteOnTermination aligns the delete on termination flag of the EBS volumes attached to the instance
+// at the given device names with the desired value, so that volumes created at launch do not outlive the instance.
+// The devices are looked up in the block device mappings of the instance and all the drifted ones are updated
+// with a single call. Device names not attached to the instance are reported as an error.
+func ensureVolumeDeleteOnTermination(client awsclient.Client, instance *ec2.Instance, deviceNames []string, desired bool) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	mappings := make(map[string]*ec2.EbsInstanceBlockDevice, len(instance.BlockDeviceMappings))
+	for _, blockDeviceMapping := range instance.BlockDeviceMappings {
+		if blockDeviceMapping.Ebs != nil {
+			mappings[aws.StringValue(blockDeviceMapping.DeviceName)] = blockDeviceMapping.Ebs
+		}
+	}
+
+	missing := []string{}
+	toModify := []*ec2.InstanceBlockDeviceMappingSpecification{}
+	for _, deviceName := range deviceNames {
+		ebs, ok := mappings[deviceName]
+		if !ok {
+			missing = append(missing, deviceName)
+			continue
+		}
+		if aws.BoolValue(ebs.DeleteOnTermination) == desired {
+			continue
+		}
+		toModify = append(toModify, &ec2.InstanceBlockDeviceMappingSpecification{
+			DeviceName: aws.String(deviceName),
+			Ebs:        &ec2.EbsInstanceBlockDeviceSpecification{DeleteOnTermination: aws.Bool(desired)},
+		})
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no EBS volume attached to instance %s at devices: %s", *instance.InstanceId, strings.Join(missing, ", "))
+	}
+	if len(toModify) == 0 {
+		return nil
+	}
+
+	klog.Infof("Setting delete on termination of %d volumes of instance %s to %t", len(toModify), *instance.InstanceId, desired)
+	_, err := client.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId:          instance.InstanceId,
+		BlockDeviceMappings: toModify,
+	})
+	if err != nil {
+		klog.Errorf("Error modifying delete on termination of volumes of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error modifying delete on termination of volumes of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	return nil
+}
+
+// ensureTerminationProtection aligns the termination protection (DisableApiTermination) attribute
+// of the instance with the desired value.
+func ensureTerminationProtection(client awsclient.Client, instance *ec2.Instance, desired bool) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	attribute, err := client.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+		InstanceId: instance.InstanceId,
+		Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+	})
+	if err != nil {
+		klog.Errorf("Error describing termination protection of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error describing termination protection of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	if attribute.DisableApiTermination != nil && aws.BoolValue(attribute.DisableApiTermination.Value) == desired {
+		return nil
+	}
+
+	klog.Infof("Setting termination protection of instance %s to %t", *instance.InstanceId, desired)
+	_, err = client.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId:            instance.InstanceId,
+		DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(desired)},
+	})
+	if err != nil {
+		klog.Errorf("Error modifying termination protection of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error modifying termination protection of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	return nil
+}
+
+// disableTerminationProtection turns termination protection off so that a machine being deleted
+// can terminate its instance, whatever the provider spec requests.
+func disableTerminationProtection(client awsclient.Client, instance *ec2.Instance) error {
+	return ensureTerminationProtection(client, instance, false)
+}
+
+const (
+	// minMetadataHopLimit and maxMetadataHopLimit bound the hop limit of the instance metadata service PUT responses
+	minMetadataHopLimit = 1
+	maxMetadataHopLimit = 64
+)
+
+// ensureMetadataHopLimit aligns the hop limit of the instance metadata service PUT responses with the desired value,
+// e.g. to let containers behind an additional network hop reach the service. Only the hop limit is changed,
+// whether tokens are required is left as it is.
+func ensureMetadataHopLimit(client awsclient.Client, instance *ec2.Instance, hopLimit int64) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if hopLimit < minMetadataHopLimit || hopLimit > maxMetadataHopLimit {
+		return fmt.Errorf("metadata hop limit %d is out of range, valid values are %d to %d", hopLimit, minMetadataHopLimit, maxMetadataHopLimit)
+	}
+	if instance.MetadataOptions != nil && aws.Int64Value(instance.MetadataOptions.HttpPutResponseHopLimit) == hopLimit {
+		return nil
+	}
+
+	klog.Infof("Setting metadata hop limit of instance %s to %d", *instance.InstanceId, hopLimit)
+	_, err := client.ModifyInstanceMetadataOptions(&ec2.ModifyInstanceMetadataOptionsInput{
+		InstanceId:              instance.InstanceId,
+		HttpPutResponseHopLimit: aws.Int64(hopLimit),
+	})
+	if err != nil {
+		klog.Errorf("Error modifying metadata hop limit of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error modifying metadata hop limit of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	return nil
+}
+
+// userDataDrift returns true if the user data of the instance differs from the desired user data,
+// e.g. after the ignition secret of the cluster was rotated.
+func userDataDrift(client awsclient.Client, instance *ec2.Instance, desiredUserData []byte) (bool, error) {
+	if instance == nil || instance.InstanceId == nil {
+		return false, fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	attribute, err := client.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+		InstanceId: instance.InstanceId,
+		Attribute:  aws.String(ec2.InstanceAttributeNameUserData),
+	})
+	if err != nil {
+		klog.Errorf("Error describing user data of instance %s: %v", *instance.InstanceId, err)
+		return false, fmt.Errorf("error describing user data of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	currentUserData := []byte{}
+	if attribute.UserData != nil {
+		currentUserData, err = base64.StdEncoding.DecodeString(aws.StringValue(attribute.UserData.Value))
+		if err != nil {
+			return false, fmt.Errorf("error decoding user data of instance %s: %v", *instance.InstanceId, err)
+		}
+	}
+	return !bytes.Equal(currentUserData, desiredUserData), nil
+}
+
+// applyUserData replaces the user data of the instance. The user data can only be changed while the instance is stopped.
+func applyUserData(client awsclient.Client, instance *ec2.Instance, userData []byte) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	state := ""
+	if instance.State != nil {
+		state = aws.StringValue(instance.State.Name)
+	}
+	if state != ec2.InstanceStateNameStopped {
+		return fmt.Errorf("instance %s must be stopped to update its user data, current state is %q", *instance.InstanceId, state)
+	}
+
+	klog.Infof("Updating user data of instance %s", *instance.InstanceId)
+	_, err := client.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId: instance.InstanceId,
+		UserData:   &ec2.BlobAttributeValue{Value: userData},
+	})
+	if err != nil {
+		klog.Errorf("Error updating user data of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error updating user data of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	return nil
+}
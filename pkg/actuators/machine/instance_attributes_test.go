@@ -0,0 +1,679 @@
+package machine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+)
+
+func TestEnsureSourceDestCheck(t *testing.T) {
+	instance := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+
+	testCases := []struct {
+		name          string
+		current       *bool
+		desired       bool
+		describeErr   error
+		expectModify  bool
+		expectedError bool
+	}{
+		{
+			name:         "Drifted to disabled",
+			current:      aws.Bool(true),
+			desired:      false,
+			expectModify: true,
+		},
+		{
+			name:         "Drifted to enabled",
+			current:      aws.Bool(false),
+			desired:      true,
+			expectModify: true,
+		},
+		{
+			name:    "Already aligned",
+			current: aws.Bool(false),
+			desired: false,
+		},
+		{
+			name:         "Attribute not reported",
+			desired:      true,
+			expectModify: true,
+		},
+		{
+			name:          "Describe error",
+			describeErr:   fmt.Errorf("error"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+
+			output := &ec2.DescribeInstanceAttributeOutput{InstanceId: instance.InstanceId}
+			if tc.current != nil {
+				output.SourceDestCheck = &ec2.AttributeBooleanValue{Value: tc.current}
+			}
+			mockAWSClient.EXPECT().DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+				InstanceId: instance.InstanceId,
+				Attribute:  aws.String(ec2.InstanceAttributeNameSourceDestCheck),
+			}).Return(output, tc.describeErr)
+			if tc.expectModify {
+				mockAWSClient.EXPECT().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+					InstanceId:      instance.InstanceId,
+					SourceDestCheck: &ec2.AttributeBooleanValue{Value: aws.Bool(tc.desired)},
+				}).Return(&ec2.ModifyInstanceAttributeOutput{}, nil)
+			}
+
+			err := ensureSourceDestCheck(mockAWSClient, instance, tc.desired)
+			if tc.expectedError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestEnsureENASupport(t *testing.T) {
+	instance := func(instanceType, state string, enaSupport bool) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId:   aws.String("i-1"),
+			InstanceType: aws.String(instanceType),
+			EnaSupport:   aws.Bool(enaSupport),
+			State:        &ec2.InstanceState{Name: aws.String(state)},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		instance     *ec2.Instance
+		expectModify bool
+		expectError  bool
+	}{
+		{
+			name:         "Disabled on a stopped ENA capable instance",
+			instance:     instance("m5.large", ec2.InstanceStateNameStopped, false),
+			expectModify: true,
+		},
+		{
+			name:        "Disabled on a running ENA capable instance",
+			instance:    instance("m5.large", ec2.InstanceStateNameRunning, false),
+			expectError: true,
+		},
+		{
+			name:     "Already enabled",
+			instance: instance("m5.large", ec2.InstanceStateNameRunning, true),
+		},
+		{
+			name:     "Instance type without ENA support",
+			instance: instance("m4.large", ec2.InstanceStateNameStopped, false),
+		},
+		{
+			name:         "ENA capable m4 size",
+			instance:     instance("m4.16xlarge", ec2.InstanceStateNameStopped, false),
+			expectModify: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectModify {
+				mockAWSClient.EXPECT().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+					InstanceId: aws.String("i-1"),
+					EnaSupport: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
+				}).Return(&ec2.ModifyInstanceAttributeOutput{}, nil)
+			}
+
+			err := ensureENASupport(mockAWSClient, tc.instance)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestReconcilePrimaryENISecurityGroups(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-secondary"),
+				Attachment:         &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(1)},
+				Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String("sg-other")}},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-primary"),
+				Attachment:         &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(0)},
+				Groups: []*ec2.GroupIdentifier{
+					{GroupId: aws.String("sg-1")},
+					{GroupId: aws.String("sg-2")},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		instance       *ec2.Instance
+		desired        []string
+		expectedGroups []string
+		expectError    bool
+	}{
+		{
+			name:           "Add a group",
+			instance:       instance,
+			desired:        []string{"sg-1", "sg-2", "sg-3"},
+			expectedGroups: []string{"sg-1", "sg-2", "sg-3"},
+		},
+		{
+			name:           "Remove a group",
+			instance:       instance,
+			desired:        []string{"sg-2"},
+			expectedGroups: []string{"sg-2"},
+		},
+		{
+			name:     "No change in a different order",
+			instance: instance,
+			desired:  []string{"sg-2", "sg-1"},
+		},
+		{
+			name:        "No desired groups",
+			instance:    instance,
+			expectError: true,
+		},
+		{
+			name:        "No primary interface",
+			instance:    &ec2.Instance{InstanceId: aws.String("i-1")},
+			desired:     []string{"sg-1"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectedGroups != nil {
+				mockAWSClient.EXPECT().ModifyNetworkInterfaceAttribute(&ec2.ModifyNetworkInterfaceAttributeInput{
+					NetworkInterfaceId: aws.String("eni-primary"),
+					Groups:             aws.StringSlice(tc.expectedGroups),
+				}).Return(&ec2.ModifyNetworkInterfaceAttributeOutput{}, nil)
+			}
+
+			err := reconcilePrimaryENISecurityGroups(mockAWSClient, tc.instance, tc.desired)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestSecurityGroupDrift(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-secondary"),
+				Attachment:         &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(1)},
+				Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String("sg-other")}},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-primary"),
+				Attachment:         &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(0)},
+				Groups: []*ec2.GroupIdentifier{
+					{GroupId: aws.String("sg-1")},
+					{GroupId: aws.String("sg-2")},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		instance        *ec2.Instance
+		desired         []string
+		expectedMissing []string
+		expectedExtra   []string
+	}{
+		{
+			name:     "No drift",
+			instance: instance,
+			desired:  []string{"sg-2", "sg-1"},
+		},
+		{
+			name:            "Overlapping groups",
+			instance:        instance,
+			desired:         []string{"sg-2", "sg-3"},
+			expectedMissing: []string{"sg-3"},
+			expectedExtra:   []string{"sg-1"},
+		},
+		{
+			name:            "Missing groups",
+			instance:        instance,
+			desired:         []string{"sg-1", "sg-2", "sg-4", "sg-3"},
+			expectedMissing: []string{"sg-3", "sg-4"},
+		},
+		{
+			name:          "Extra groups",
+			instance:      instance,
+			desired:       []string{"sg-1"},
+			expectedExtra: []string{"sg-2"},
+		},
+		{
+			name:            "No primary interface",
+			instance:        &ec2.Instance{InstanceId: aws.String("i-1")},
+			desired:         []string{"sg-1"},
+			expectedMissing: []string{"sg-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			missing, extra := securityGroupDrift(tc.instance, tc.desired)
+			if !reflect.DeepEqual(missing, tc.expectedMissing) {
+				t.Errorf("Expected missing groups %v, got %v", tc.expectedMissing, missing)
+			}
+			if !reflect.DeepEqual(extra, tc.expectedExtra) {
+				t.Errorf("Expected extra groups %v, got %v", tc.expectedExtra, extra)
+			}
+		})
+	}
+}
+
+func TestReconcileIPv6AddressCount(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-primary"),
+				Attachment:         &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(0)},
+				Ipv6Addresses: []*ec2.InstanceIpv6Address{
+					{Ipv6Address: aws.String("2600:1f18::b")},
+					{Ipv6Address: aws.String("2600:1f18::a")},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		desired      int
+		expectations func(*mockaws.MockClientMockRecorder)
+		expectError  bool
+	}{
+		{
+			name:    "Assign missing addresses",
+			desired: 4,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.AssignIpv6Addresses(&ec2.AssignIpv6AddressesInput{
+					NetworkInterfaceId: aws.String("eni-primary"),
+					Ipv6AddressCount:   aws.Int64(2),
+				}).Return(&ec2.AssignIpv6AddressesOutput{}, nil)
+			},
+		},
+		{
+			name:    "Unassign extra addresses",
+			desired: 1,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.UnassignIpv6Addresses(&ec2.UnassignIpv6AddressesInput{
+					NetworkInterfaceId: aws.String("eni-primary"),
+					Ipv6Addresses:      aws.StringSlice([]string{"2600:1f18::b"}),
+				}).Return(&ec2.UnassignIpv6AddressesOutput{}, nil)
+			},
+		},
+		{
+			name:         "Already aligned",
+			desired:      2,
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+		},
+		{
+			name:         "Invalid desired count",
+			desired:      0,
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := reconcileIPv6AddressCount(mockAWSClient, instance, tc.desired)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestEnsureVolumeDeleteOnTermination(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsInstanceBlockDevice{DeleteOnTermination: aws.Bool(true)}},
+			{DeviceName: aws.String("/dev/xvdb"), Ebs: &ec2.EbsInstanceBlockDevice{DeleteOnTermination: aws.Bool(false)}},
+			{DeviceName: aws.String("/dev/xvdc"), Ebs: &ec2.EbsInstanceBlockDevice{DeleteOnTermination: aws.Bool(false)}},
+		},
+	}
+	mapping := func(deviceName string, desired bool) *ec2.InstanceBlockDeviceMappingSpecification {
+		return &ec2.InstanceBlockDeviceMappingSpecification{
+			DeviceName: aws.String(deviceName),
+			Ebs:        &ec2.EbsInstanceBlockDeviceSpecification{DeleteOnTermination: aws.Bool(desired)},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		deviceNames      []string
+		desired          bool
+		expectedMappings []*ec2.InstanceBlockDeviceMappingSpecification
+		expectError      bool
+	}{
+		{
+			name:             "Enable on drifted devices only",
+			deviceNames:      []string{"/dev/xvda", "/dev/xvdb", "/dev/xvdc"},
+			desired:          true,
+			expectedMappings: []*ec2.InstanceBlockDeviceMappingSpecification{mapping("/dev/xvdb", true), mapping("/dev/xvdc", true)},
+		},
+		{
+			name:             "Disable on drifted devices only",
+			deviceNames:      []string{"/dev/xvda", "/dev/xvdb"},
+			desired:          false,
+			expectedMappings: []*ec2.InstanceBlockDeviceMappingSpecification{mapping("/dev/xvda", false)},
+		},
+		{
+			name:        "Already aligned",
+			deviceNames: []string{"/dev/xvdb", "/dev/xvdc"},
+			desired:     false,
+		},
+		{
+			name:        "Unknown device",
+			deviceNames: []string{"/dev/xvdb", "/dev/xvdz"},
+			desired:     true,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectedMappings != nil {
+				mockAWSClient.EXPECT().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+					InstanceId:          aws.String("i-1"),
+					BlockDeviceMappings: tc.expectedMappings,
+				}).Return(&ec2.ModifyInstanceAttributeOutput{}, nil)
+			}
+
+			err := ensureVolumeDeleteOnTermination(mockAWSClient, instance, tc.deviceNames, tc.desired)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestEnsureTerminationProtection(t *testing.T) {
+	instance := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+
+	testCases := []struct {
+		name          string
+		current       *bool
+		desired       bool
+		deleting      bool
+		describeErr   error
+		expectModify  bool
+		expectedValue bool
+		expectedError bool
+	}{
+		{
+			name:          "Drifted to unprotected",
+			current:       aws.Bool(false),
+			desired:       true,
+			expectModify:  true,
+			expectedValue: true,
+		},
+		{
+			name:          "Drifted to protected",
+			current:       aws.Bool(true),
+			desired:       false,
+			expectModify:  true,
+			expectedValue: false,
+		},
+		{
+			name:    "Already protected",
+			current: aws.Bool(true),
+			desired: true,
+		},
+		{
+			name:          "Deletion disables protection requested by the spec",
+			current:       aws.Bool(true),
+			desired:       true,
+			deleting:      true,
+			expectModify:  true,
+			expectedValue: false,
+		},
+		{
+			name:     "Deletion with protection already disabled",
+			current:  aws.Bool(false),
+			deleting: true,
+		},
+		{
+			name:          "Describe error",
+			describeErr:   fmt.Errorf("error"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+
+			output := &ec2.DescribeInstanceAttributeOutput{InstanceId: instance.InstanceId}
+			if tc.current != nil {
+				output.DisableApiTermination = &ec2.AttributeBooleanValue{Value: tc.current}
+			}
+			mockAWSClient.EXPECT().DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+				InstanceId: instance.InstanceId,
+				Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+			}).Return(output, tc.describeErr)
+			if tc.expectModify {
+				mockAWSClient.EXPECT().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+					InstanceId:            instance.InstanceId,
+					DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(tc.expectedValue)},
+				}).Return(&ec2.ModifyInstanceAttributeOutput{}, nil)
+			}
+
+			var err error
+			if tc.deleting {
+				err = disableTerminationProtection(mockAWSClient, instance)
+			} else {
+				err = ensureTerminationProtection(mockAWSClient, instance, tc.desired)
+			}
+			if tc.expectedError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestEnsureMetadataHopLimit(t *testing.T) {
+	instance := func(hopLimit int64) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId: aws.String("i-1"),
+			MetadataOptions: &ec2.InstanceMetadataOptionsResponse{
+				HttpTokens:              aws.String(ec2.HttpTokensStateOptional),
+				HttpPutResponseHopLimit: aws.Int64(hopLimit),
+			},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		instance     *ec2.Instance
+		hopLimit     int64
+		expectModify bool
+		expectError  bool
+	}{
+		{
+			name:     "Hop limit already set",
+			instance: instance(2),
+			hopLimit: 2,
+		},
+		{
+			name:         "Hop limit drift",
+			instance:     instance(1),
+			hopLimit:     2,
+			expectModify: true,
+		},
+		{
+			name:         "No metadata options",
+			instance:     &ec2.Instance{InstanceId: aws.String("i-1")},
+			hopLimit:     3,
+			expectModify: true,
+		},
+		{
+			name:        "Hop limit too low",
+			instance:    instance(1),
+			hopLimit:    0,
+			expectError: true,
+		},
+		{
+			name:        "Hop limit too high",
+			instance:    instance(1),
+			hopLimit:    65,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectModify {
+				mockAWSClient.EXPECT().ModifyInstanceMetadataOptions(&ec2.ModifyInstanceMetadataOptionsInput{
+					InstanceId:              aws.String("i-1"),
+					HttpPutResponseHopLimit: aws.Int64(tc.hopLimit),
+				}).Return(&ec2.ModifyInstanceMetadataOptionsOutput{}, nil)
+			}
+
+			err := ensureMetadataHopLimit(mockAWSClient, tc.instance, tc.hopLimit)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestUserDataDrift(t *testing.T) {
+	instance := &ec2.Instance{InstanceId: aws.String("i-1")}
+	desired := []byte(`{"ignition":{"version":"3.2.0"}}`)
+
+	testCases := []struct {
+		name          string
+		userData      *ec2.AttributeValue
+		expectedDrift bool
+		expectError   bool
+	}{
+		{
+			name:     "Same user data",
+			userData: &ec2.AttributeValue{Value: aws.String(base64.StdEncoding.EncodeToString(desired))},
+		},
+		{
+			name:          "Stale user data",
+			userData:      &ec2.AttributeValue{Value: aws.String(base64.StdEncoding.EncodeToString([]byte(`{"ignition":{"version":"3.1.0"}}`)))},
+			expectedDrift: true,
+		},
+		{
+			name:          "No user data",
+			expectedDrift: true,
+		},
+		{
+			name:        "Invalid user data encoding",
+			userData:    &ec2.AttributeValue{Value: aws.String("not base64!")},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+				InstanceId: aws.String("i-1"),
+				Attribute:  aws.String(ec2.InstanceAttributeNameUserData),
+			}).Return(&ec2.DescribeInstanceAttributeOutput{UserData: tc.userData}, nil)
+
+			drift, err := userDataDrift(mockAWSClient, instance, desired)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if drift != tc.expectedDrift {
+				t.Errorf("Expected drift: %v, got: %v", tc.expectedDrift, drift)
+			}
+		})
+	}
+}
+
+func TestApplyUserData(t *testing.T) {
+	userData := []byte(`{"ignition":{"version":"3.2.0"}}`)
+	instance := func(state string) *ec2.Instance {
+		return &ec2.Instance{InstanceId: aws.String("i-1"), State: &ec2.InstanceState{Name: aws.String(state)}}
+	}
+
+	testCases := []struct {
+		name         string
+		instance     *ec2.Instance
+		expectModify bool
+		expectError  bool
+	}{
+		{
+			name:         "Stopped instance",
+			instance:     instance(ec2.InstanceStateNameStopped),
+			expectModify: true,
+		},
+		{
+			name:        "Running instance",
+			instance:    instance(ec2.InstanceStateNameRunning),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectModify {
+				mockAWSClient.EXPECT().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+					InstanceId: aws.String("i-1"),
+					UserData:   &ec2.BlobAttributeValue{Value: userData},
+				}).Return(&ec2.ModifyInstanceAttributeOutput{}, nil)
+			}
+
+			err := applyUserData(mockAWSClient, tc.instance, userData)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -17,10 +20,17 @@ import (
 	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
 	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// maxTagKeyLength and maxTagValueLength are the limits EC2 puts on tag keys and values
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
 // Scan machine tags, and return a deduped tags list. The first found value gets precedence.
 func removeDuplicatedTags(tags []*ec2.Tag) []*ec2.Tag {
 	m := make(map[string]bool)
@@ -150,6 +160,36 @@ func getSubnetIDs(machine runtimeclient.ObjectKey, subnet machinev1.AWSResourceR
 	return subnetIDs, nil
 }
 
+// describeSubnet returns the subnet with the given ID, or an error naming the subnet if it does not exist.
+func describeSubnet(client awsclient.Client, subnetID string) (*ec2.Subnet, error) {
+	result, err := client.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice([]string{subnetID}),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidSubnetID.NotFound" {
+			return nil, fmt.Errorf("subnet %q not found", subnetID)
+		}
+		klog.Errorf("Error describing subnet %q: %v", subnetID, err)
+		return nil, fmt.Errorf("error describing subnet %q: %v", subnetID, err)
+	}
+	if len(result.Subnets) == 0 {
+		return nil, fmt.Errorf("subnet %q not found", subnetID)
+	}
+	return result.Subnets[0], nil
+}
+
+// vpcIDForSubnet returns the ID of the VPC the subnet belongs to.
+func vpcIDForSubnet(client awsclient.Client, subnetID string) (string, error) {
+	subnet, err := describeSubnet(client, subnetID)
+	if err != nil {
+		return "", err
+	}
+	if subnet.VpcId == nil {
+		return "", fmt.Errorf("subnet %q has no VPC ID", subnetID)
+	}
+	return *subnet.VpcId, nil
+}
+
 func getAMI(machine runtimeclient.ObjectKey, AMI machinev1.AWSResourceReference, client awsclient.Client) (*string, error) {
 	if AMI.ID != nil {
 		amiID := AMI.ID
@@ -278,11 +318,21 @@ func getBlockDeviceMappings(machine runtimeclient.ObjectKey, blockDeviceMappingS
 	return blockDeviceMappings, nil
 }
 
-func launchInstance(machine *machinev1.Machine, machineProviderConfig *machinev1.AWSMachineProviderConfig, userData []byte, client awsclient.Client, infra *configv1.Infrastructure) (*ec2.Instance, error) {
+// launchInstance launches the instance of the machine. userTags are the user tags of the instance,
+// as computed by mergeEffectiveTags, to which the tags managed by the machine controller are added.
+func launchInstance(machine *machinev1.Machine, machineProviderConfig *machinev1.AWSMachineProviderConfig, userData []byte, client awsclient.Client, userTags map[string]string) (*ec2.Instance, error) {
 	machineKey := runtimeclient.ObjectKey{
 		Name:      machine.Name,
 		Namespace: machine.Namespace,
 	}
+	availabilityZone, err := normalizeAvailabilityZone(machineProviderConfig.Placement.AvailabilityZone)
+	if err != nil {
+		return nil, mapierrors.InvalidMachineConfiguration("error validating placement: %v", err)
+	}
+	if err := validateUserDataSize(userData); err != nil {
+		return nil, mapierrors.InvalidMachineConfiguration("error validating user data: %v", err)
+	}
+
 	amiID, err := getAMI(machineKey, machineProviderConfig.AMI, client)
 	if err != nil {
 		return nil, mapierrors.InvalidMachineConfiguration("error getting AMI: %v", err)
@@ -292,7 +342,7 @@ func launchInstance(machine *machinev1.Machine, machineProviderConfig *machinev1
 	if err != nil {
 		return nil, mapierrors.InvalidMachineConfiguration("error getting security groups IDs: %v", err)
 	}
-	subnetIDs, err := getSubnetIDs(machineKey, machineProviderConfig.Subnet, machineProviderConfig.Placement.AvailabilityZone, client)
+	subnetIDs, err := getSubnetIDs(machineKey, machineProviderConfig.Subnet, availabilityZone, client)
 	if err != nil {
 		return nil, mapierrors.InvalidMachineConfiguration("error getting subnet IDs: %v", err)
 	}
@@ -321,7 +371,10 @@ func launchInstance(machine *machinev1.Machine, machineProviderConfig *machinev1
 		return nil, mapierrors.InvalidMachineConfiguration("Unable to get cluster ID for machine: %q", machine.Name)
 	}
 	// Add tags to the created machine
-	tagList := buildTagList(machine.Name, clusterID, machineProviderConfig.Tags, infra)
+	tagList := buildInstanceTagList(machine.Name, clusterID, userTags)
+	if err := validateTagCount(tagsToMap(tagList)); err != nil {
+		return nil, mapierrors.InvalidMachineConfiguration("error validating tags: %v", err)
+	}
 
 	tagInstance := &ec2.TagSpecification{
 		ResourceType: aws.String("instance"),
@@ -342,9 +395,9 @@ func launchInstance(machine *machinev1.Machine, machineProviderConfig *machinev1
 	}
 
 	var placement *ec2.Placement
-	if machineProviderConfig.Placement.AvailabilityZone != "" && machineProviderConfig.Subnet.ID == nil {
+	if availabilityZone != "" && machineProviderConfig.Subnet.ID == nil {
 		placement = &ec2.Placement{
-			AvailabilityZone: aws.String(machineProviderConfig.Placement.AvailabilityZone),
+			AvailabilityZone: aws.String(availabilityZone),
 		}
 	}
 
@@ -396,7 +449,16 @@ func launchInstance(machine *machinev1.Machine, machineProviderConfig *machinev1
 		// https://tools.ietf.org/html/rfc2616#section-6.1.1
 		// https: //docs.aws.amazon.com/AWSEC2/latest/APIReference/errors-overview.html
 		// https://docs.aws.amazon.com/sdk-for-go/api/aws/awserr/
-		if _, ok := err.(awserr.Error); ok {
+		if aerr, ok := err.(awserr.Error); ok {
+			// Quota errors are 4xx errors too, but they are not caused by the machine configuration
+			// and launching may succeed once the quota is raised.
+			if isQuotaExceededErrorCode(aerr.Code()) {
+				klog.Infof("Error launching instance, quota exceeded: %v", aerr)
+				return nil, &quotaExceededError{
+					quota: aerr.Code(),
+					err:   mapierrors.CreateMachine("error launching instance: %v", aerr.Message()),
+				}
+			}
 			if reqErr, ok := err.(awserr.RequestFailure); ok {
 				if strings.HasPrefix(strconv.Itoa(reqErr.StatusCode()), "4") {
 					klog.Infof("Error launching instance: %v", reqErr)
@@ -436,6 +498,22 @@ func buildTagList(machineName string, clusterID string, machineTags []machinev1.
 	return removeDuplicatedTags(rawTagList)
 }
 
+// buildInstanceTagList compiles the list of ec2 tags of an instance from its user tags, sorted by key,
+// and the tags managed by the machine controller.
+func buildInstanceTagList(machineName string, clusterID string, userTags map[string]string) []*ec2.Tag {
+	keys := make([]string, 0, len(userTags))
+	for key := range userTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := make([]machinev1.TagSpecification, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, machinev1.TagSpecification{Name: key, Value: userTags[key]})
+	}
+	return buildTagList(machineName, clusterID, tags, nil)
+}
+
 // mergeInfrastructureAndMachineSpecTags merge list of tags from machine provider spec and Infrastructure object platform spec.
 // Machine tags have precedence over Infrastructure
 func mergeInfrastructureAndMachineSpecTags(machineSpecTags []machinev1.TagSpecification, infra *configv1.Infrastructure) []machinev1.TagSpecification {
@@ -453,6 +531,150 @@ func mergeInfrastructureAndMachineSpecTags(machineSpecTags []machinev1.TagSpecif
 	return mergedList
 }
 
+// mergeEffectiveTags computes the set of user tags that should be present on the instance.
+// Tags from the machine provider spec take precedence over tags from the Infrastructure object,
+// which take precedence over the tags mirrored from the machine labels by tagsFromMachineLabels,
+// when they define the same key. Every tag is validated with validateUserTag, and a provider spec
+// defining the same key twice with different values is rejected.
+// The result can be passed to correctExistingTags.
+func mergeEffectiveTags(labelTags, infraTags map[string]string, specTags []machinev1.TagSpecification) (map[string]string, error) {
+	effectiveTags := make(map[string]string, len(labelTags)+len(infraTags)+len(specTags))
+	for key, value := range labelTags {
+		effectiveTags[key] = value
+	}
+	for key, value := range infraTags {
+		if err := validateUserTag(key, value); err != nil {
+			return nil, fmt.Errorf("invalid infrastructure tag: %v", err)
+		}
+		effectiveTags[key] = value
+	}
+
+	specKeys := make(map[string]string, len(specTags))
+	for _, tag := range specTags {
+		if err := validateUserTag(tag.Name, tag.Value); err != nil {
+			return nil, fmt.Errorf("invalid provider spec tag: %v", err)
+		}
+		if value, ok := specKeys[tag.Name]; ok && value != tag.Value {
+			return nil, fmt.Errorf("provider spec tag %q is defined with conflicting values %q and %q", tag.Name, value, tag.Value)
+		}
+		specKeys[tag.Name] = tag.Value
+		effectiveTags[tag.Name] = tag.Value
+	}
+
+	return effectiveTags, nil
+}
+
+// tagsFromMachineLabels returns the labels of the machine whose key starts with any of the prefixes as tags,
+// e.g. for cost allocation. Labels that are not valid user tags are skipped.
+func tagsFromMachineLabels(machine *machinev1.Machine, prefixes []string) map[string]string {
+	tags := map[string]string{}
+	if machine == nil {
+		return tags
+	}
+
+	for key, value := range machine.Labels {
+		matches := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if err := validateUserTag(key, value); err != nil {
+			klog.V(4).Infof("%s: Skipping label %q as instance tag: %v", machine.Name, key, err)
+			continue
+		}
+		tags[key] = value
+	}
+	return tags
+}
+
+// validateUserTag checks that a user defined tag is accepted by EC2 and
+// does not clash with the tags managed by the machine controller.
+func validateUserTag(key, value string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("tag key must not be empty")
+	case utf8.RuneCountInString(key) > maxTagKeyLength:
+		return fmt.Errorf("tag key %q is longer than %d characters", key, maxTagKeyLength)
+	case utf8.RuneCountInString(value) > maxTagValueLength:
+		return fmt.Errorf("value of tag %q is longer than %d characters", key, maxTagValueLength)
+	case strings.HasPrefix(strings.ToLower(key), "aws:"):
+		return fmt.Errorf("tag key %q uses the reserved aws: prefix", key)
+	case key == "Name" || key == nodeNameTagKey || strings.HasPrefix(key, "kubernetes.io/cluster/"):
+		return fmt.Errorf("tag key %q is managed by the machine controller", key)
+	}
+	return nil
+}
+
+// validateTagPolicy checks the tags against the naming policy of an organization, on top of validateUserTag:
+// every key has to match keyPattern and every value valuePattern. A nil pattern is not enforced.
+// All the violations are returned in an aggregated error.
+func validateTagPolicy(tags map[string]string, keyPattern, valuePattern *regexp.Regexp) error {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := []error{}
+	for _, key := range keys {
+		if keyPattern != nil && !keyPattern.MatchString(key) {
+			errs = append(errs, fmt.Errorf("tag key %q does not match the tag policy pattern %q", key, keyPattern.String()))
+		}
+		if valuePattern != nil && !valuePattern.MatchString(tags[key]) {
+			errs = append(errs, fmt.Errorf("value of tag %q does not match the tag policy pattern %q", key, valuePattern.String()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// validateExpandedTag checks a user defined tag whose value is templated, e.g. with the machine name.
+// The raw value may fit within the EC2 limits while its expansion does not, so the expanded value
+// is checked as well: its length in characters and that it is valid UTF-8 without control characters.
+func validateExpandedTag(key, rawValue, expanded string) error {
+	if err := validateUserTag(key, rawValue); err != nil {
+		return err
+	}
+	switch {
+	case !utf8.ValidString(expanded):
+		return fmt.Errorf("expanded value of tag %q is not valid UTF-8", key)
+	case utf8.RuneCountInString(expanded) > maxTagValueLength:
+		return fmt.Errorf("expanded value of tag %q is %d characters long, longer than %d characters", key, utf8.RuneCountInString(expanded), maxTagValueLength)
+	case strings.IndexFunc(expanded, unicode.IsControl) >= 0:
+		return fmt.Errorf("expanded value of tag %q contains control characters", key)
+	}
+	return nil
+}
+
+// reservedInstanceTagCount is the number of tags the machine controller adds to every instance:
+// the Name tag and the cluster ownership tag.
+const reservedInstanceTagCount = 2
+
+// validateTagCount checks that the user tags together with the tags managed by the machine controller
+// fit within the EC2 limit of tags per resource. User tags overriding the managed tags are dropped by
+// buildTagList and are not counted.
+func validateTagCount(userTags map[string]string) error {
+	count := reservedInstanceTagCount
+	for key := range userTags {
+		if key != "Name" && !strings.HasPrefix(key, "kubernetes.io/cluster/") {
+			count++
+		}
+	}
+	if count > maxInstanceTags {
+		return fmt.Errorf("instance would have %d tags (%d user defined and %d managed by the machine controller), %d over the limit of %d",
+			count, count-reservedInstanceTagCount, reservedInstanceTagCount, count-maxInstanceTags, maxInstanceTags)
+	}
+	return nil
+}
+
 type instanceList []*ec2.Instance
 
 func (il instanceList) Len() int {
@@ -513,3 +735,34 @@ func getInstanceMarketOptionsRequest(providerConfig *machinev1.AWSMachineProvide
 
 	return instanceMarketOptionsRequest
 }
+
+// tagSpotInstanceRequest applies the tags to the spot instance request that backs the instance,
+// so that the request shows up alongside the instance in cost reports.
+// On-demand instances have no spot instance request and are skipped.
+func tagSpotInstanceRequest(client awsclient.Client, instance *ec2.Instance, tags map[string]string) error {
+	if instance == nil || instance.SpotInstanceRequestId == nil || len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tagList := []*ec2.Tag{}
+	for _, key := range keys {
+		tagList = append(tagList, &ec2.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+
+	_, err := client.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{instance.SpotInstanceRequestId},
+		Tags:      tagList,
+	})
+	if err != nil {
+		klog.Errorf("Error tagging spot instance request %s: %v", *instance.SpotInstanceRequestId, err)
+		return fmt.Errorf("error tagging spot instance request %s: %v", *instance.SpotInstanceRequestId, err)
+	}
+
+	return nil
+}
@@ -1,17 +1,26 @@
 package machine
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/golang/mock/gomock"
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -443,7 +452,11 @@ func TestLaunchInstance(t *testing.T) {
 	}
 
 	providerConfig := stubProviderConfig()
-	stubTagList := buildTagList(machine.Name, stubClusterID, providerConfig.Tags, nil)
+	userTags, err := mergeEffectiveTags(nil, nil, providerConfig.Tags)
+	if err != nil {
+		t.Fatalf("Unable to merge stub tags: %v", err)
+	}
+	stubTagList := buildInstanceTagList(machine.Name, stubClusterID, userTags)
 
 	infra := &configv1.Infrastructure{
 		Status: configv1.InfrastructureStatus{
@@ -460,7 +473,11 @@ func TestLaunchInstance(t *testing.T) {
 		},
 	}
 
-	stubTagListWithInfraObject := buildTagList(machine.Name, stubClusterID, providerConfig.Tags, infra)
+	userTagsWithInfraObject, err := mergeEffectiveTags(nil, tagsFromInfrastructure(infra), providerConfig.Tags)
+	if err != nil {
+		t.Fatalf("Unable to merge stub tags: %v", err)
+	}
+	stubTagListWithInfraObject := buildInstanceTagList(machine.Name, stubClusterID, userTagsWithInfraObject)
 
 	cases := []struct {
 		name                string
@@ -849,6 +866,10 @@ func TestLaunchInstance(t *testing.T) {
 			name:           "Dedicated instance tenancy",
 			providerConfig: stubInvalidInstanceTenancy(),
 		},
+		{
+			name:           "Malformed availability zone",
+			providerConfig: stubPCAvailabilityZone("us-east-1"),
+		},
 		{
 			name:           "Attach infrastructure object tags",
 			providerConfig: providerConfig,
@@ -898,7 +919,11 @@ func TestLaunchInstance(t *testing.T) {
 			mockAWSClient.EXPECT().DescribeImages(gomock.Any()).Return(tc.imageOutput, tc.imageErr).AnyTimes()
 			mockAWSClient.EXPECT().RunInstances(tc.runInstancesInput).Return(tc.instancesOutput, tc.instancesErr).AnyTimes()
 
-			_, launchErr := launchInstance(machine, tc.providerConfig, nil, mockAWSClient, tc.infra)
+			tags, err := mergeEffectiveTags(nil, tagsFromInfrastructure(tc.infra), tc.providerConfig.Tags)
+			if err != nil {
+				t.Fatalf("Unable to merge tags: %v", err)
+			}
+			_, launchErr := launchInstance(machine, tc.providerConfig, nil, mockAWSClient, tags)
 			t.Log(launchErr)
 			if launchErr == nil {
 				if !tc.succeeds {
@@ -913,6 +938,36 @@ func TestLaunchInstance(t *testing.T) {
 	}
 }
 
+func TestLaunchInstanceTagCount(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	providerConfig := stubProviderConfig()
+	userTags := map[string]string{}
+	for i := 0; i < 49; i++ {
+		userTags[fmt.Sprintf("tag-%d", i)] = "value"
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockAWSClient := mockaws.NewMockClient(mockCtrl)
+	mockAWSClient.EXPECT().DescribeSecurityGroups(gomock.Any()).Return(&ec2.DescribeSecurityGroupsOutput{}, nil).AnyTimes()
+	mockAWSClient.EXPECT().RunInstances(gomock.Any()).Times(0)
+
+	_, launchErr := launchInstance(machine, providerConfig, nil, mockAWSClient, userTags)
+	if launchErr == nil {
+		t.Fatal("Expected launchInstance to fail")
+	}
+	var machineErr *machinecontroller.MachineError
+	if !errors.As(launchErr, &machineErr) || machineErr.Reason != machinev1.InvalidConfigurationMachineError {
+		t.Errorf("Expected an invalid configuration error, got: %v", launchErr)
+	}
+	if !strings.Contains(launchErr.Error(), "over the limit of 50") {
+		t.Errorf("Expected the tag count error, got: %v", launchErr)
+	}
+}
+
 func TestSortInstances(t *testing.T) {
 	instances := []*ec2.Instance{
 		{
@@ -1059,6 +1114,25 @@ func TestCorrectExistingTags(t *testing.T) {
 			expectedCreateTags: true,
 			userTags:           map[string]string{"UserDefinedTag1": "ModifiedValue"},
 		},
+		{
+			name: "User defined tag value with trailing whitespace",
+			tags: []*ec2.Tag{
+				{
+					Key:   aws.String("kubernetes.io/cluster/" + clusterID),
+					Value: aws.String("owned"),
+				},
+				{
+					Key:   aws.String("Name"),
+					Value: aws.String(machine.Name),
+				},
+				{
+					Key:   aws.String("UserDefinedTag1"),
+					Value: aws.String("UserDefinedTagValue1"),
+				},
+			},
+			expectedCreateTags: false,
+			userTags:           map[string]string{"UserDefinedTag1": "UserDefinedTagValue1  "},
+		},
 		{
 			name: "Invalid Name Tag Correct Cluster",
 			tags: []*ec2.Tag{
@@ -1126,3 +1200,839 @@ func TestCorrectExistingTags(t *testing.T) {
 		})
 	}
 }
+
+func TestCorrectExistingTagsTagLimitExceeded(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	clusterID, _ := getClusterID(machine)
+	instance := ec2.Instance{
+		InstanceId: aws.String(stubInstanceID),
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("kubernetes.io/cluster/" + clusterID),
+				Value: aws.String("owned"),
+			},
+			{
+				Key:   aws.String("Name"),
+				Value: aws.String(machine.Name),
+			},
+		},
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockAWSClient := mockaws.NewMockClient(mockCtrl)
+	mockAWSClient.EXPECT().CreateTags(gomock.Any()).Return(nil, awserr.New("TagLimitExceeded", "The maximum number of Tags for a resource has been reached.", nil))
+
+	err = correctExistingTags(machine, &instance, mockAWSClient, map[string]string{"UserDefinedTag2": "value2", "UserDefinedTag1": "value1"})
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	var machineErr *machinecontroller.MachineError
+	if !errors.As(err, &machineErr) || machineErr.Reason != machinev1.InvalidConfigurationMachineError {
+		t.Errorf("Expected invalid configuration error, got: %v", err)
+	}
+	expected := "instance " + stubInstanceID + " would exceed the EC2 limit of 50 tags per resource, unable to apply tags: UserDefinedTag1, UserDefinedTag2"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestCorrectExistingTagsRetriesThrottling(t *testing.T) {
+	defaultBackoff := tagRetryBackoff
+	tagRetryBackoff.Duration = time.Millisecond
+	defer func() { tagRetryBackoff = defaultBackoff }()
+
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	throttled := awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+	unauthorized := awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+
+	testCases := []struct {
+		name          string
+		results       []error
+		expectedError error
+	}{
+		{
+			name:    "Throttled then success",
+			results: []error{throttled, nil},
+		},
+		{
+			name:          "Persistently throttled",
+			results:       []error{throttled, throttled, throttled},
+			expectedError: throttled,
+		},
+		{
+			name:          "Terminal error is not retried",
+			results:       []error{unauthorized},
+			expectedError: unauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			calls := []*gomock.Call{}
+			for _, result := range tc.results {
+				calls = append(calls, mockAWSClient.EXPECT().CreateTags(gomock.Any()).Return(&ec2.CreateTagsOutput{}, result))
+			}
+			gomock.InOrder(calls...)
+
+			instance := &ec2.Instance{InstanceId: aws.String(stubInstanceID)}
+			err := correctExistingTags(machine, instance, mockAWSClient, map[string]string{"UserDefinedTag1": "value1"})
+			if tc.expectedError == nil {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestCorrectExistingTagsUnauthorized(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	unauthorized := awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+
+	testCases := []struct {
+		name          string
+		skip          bool
+		expectedError bool
+	}{
+		{
+			name:          "Unauthorized tagging fails the reconcile",
+			expectedError: true,
+		},
+		{
+			name: "Unauthorized tagging is skipped when configured",
+			skip: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().CreateTags(gomock.Any()).Return(nil, unauthorized)
+
+			r := newReconciler(&machineScope{
+				awsClient:               mockAWSClient,
+				machine:                 machine,
+				providerStatus:          &machinev1.AWSMachineProviderStatus{},
+				skipUnauthorizedTagging: tc.skip,
+			})
+			instance := &ec2.Instance{InstanceId: aws.String(stubInstanceID)}
+
+			err := r.correctExistingTags(instance, map[string]string{"UserDefinedTag1": "value1"})
+			if tc.expectedError != (err != nil) {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				var unauthorizedErr *taggingUnauthorizedError
+				if !errors.As(err, &unauthorizedErr) {
+					t.Errorf("Expected an unauthorized tagging error, got: %v", err)
+				}
+			}
+
+			condition := findProviderCondition(r.providerStatus.Conditions, InstanceTagged)
+			if condition == nil {
+				t.Fatal("Expected an InstanceTagged condition")
+			}
+			if condition.Status != corev1.ConditionFalse || condition.Reason != InstanceTaggingUnauthorizedConditionReason {
+				t.Errorf("Expected an unauthorized InstanceTagged condition, got %+v", condition)
+			}
+			if !strings.Contains(condition.Message, "not authorized to tag instance "+stubInstanceID) {
+				t.Errorf("Expected an actionable message, got %q", condition.Message)
+			}
+		})
+	}
+}
+
+func TestCorrectExistingTagsTagPolicyViolation(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	clusterID, _ := getClusterID(machine)
+	violation := func(key string) error {
+		return awserr.New("TagPolicyViolation", "The tag policy does not allow the specified value for the following tag key: '"+key+"'.", nil)
+	}
+	nameTag := &ec2.Tag{Key: aws.String("Name"), Value: aws.String(machine.Name)}
+	userTag := &ec2.Tag{Key: aws.String("UserDefinedTag1"), Value: aws.String("value1")}
+	clusterTag := &ec2.Tag{Key: aws.String("kubernetes.io/cluster/" + clusterID), Value: aws.String("owned")}
+	createTags := func(tags ...*ec2.Tag) *ec2.CreateTagsInput {
+		return &ec2.CreateTagsInput{Resources: aws.StringSlice([]string{stubInstanceID}), Tags: tags}
+	}
+
+	testCases := []struct {
+		name            string
+		expectations    func(*mockaws.MockClientMockRecorder)
+		expectedError   bool
+		expectedMessage string
+	}{
+		{
+			name: "Offending user tag is skipped",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				gomock.InOrder(
+					m.CreateTags(createTags(nameTag, userTag, clusterTag)).Return(nil, violation("UserDefinedTag1")),
+					m.CreateTags(createTags(nameTag, clusterTag)).Return(&ec2.CreateTagsOutput{}, nil),
+				)
+			},
+			expectedMessage: "tag \"UserDefinedTag1\" of instance " + stubInstanceID + " violates the tag policy of the organization, the other tags were applied",
+		},
+		{
+			name: "Offending Name tag fails the reconcile",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.CreateTags(createTags(nameTag, userTag, clusterTag)).Return(nil, violation("Name"))
+			},
+			expectedError:   true,
+			expectedMessage: "tag \"Name\" of instance " + stubInstanceID + " violates the tag policy of the organization",
+		},
+		{
+			name: "Unidentified offending tag fails the reconcile",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.CreateTags(gomock.Any()).Return(nil, awserr.New("TagPolicyViolation", "The tag policy does not allow the specified tags.", nil))
+			},
+			expectedError:   true,
+			expectedMessage: "tags of instance " + stubInstanceID + " violate the tag policy of the organization",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			r := newReconciler(&machineScope{
+				awsClient:      mockAWSClient,
+				machine:        machine,
+				providerStatus: &machinev1.AWSMachineProviderStatus{},
+			})
+			instance := &ec2.Instance{InstanceId: aws.String(stubInstanceID)}
+
+			err := r.correctExistingTags(instance, map[string]string{"UserDefinedTag1": "value1"})
+			if tc.expectedError != (err != nil) {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+
+			condition := findProviderCondition(r.providerStatus.Conditions, InstanceTagged)
+			if condition == nil {
+				t.Fatal("Expected an InstanceTagged condition")
+			}
+			if condition.Status != corev1.ConditionFalse || condition.Reason != InstanceTagPolicyViolationConditionReason {
+				t.Errorf("Expected a tag policy violation InstanceTagged condition, got %+v", condition)
+			}
+			if !strings.HasPrefix(condition.Message, tc.expectedMessage) {
+				t.Errorf("Expected message starting with %q, got %q", tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestCorrectTagsForInstances(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	clusterID, _ := getClusterID(machine)
+
+	correctTags := []*ec2.Tag{
+		{Key: aws.String("kubernetes.io/cluster/" + clusterID), Value: aws.String("owned")},
+		{Key: aws.String("Name"), Value: aws.String(machine.Name)},
+		{Key: aws.String("UserDefinedTag1"), Value: aws.String("UserDefinedTagValue1")},
+	}
+	instances := []*ec2.Instance{
+		{InstanceId: aws.String("i-correct-1"), Tags: correctTags},
+		{InstanceId: aws.String("i-missing-all"), Tags: nil},
+		{InstanceId: aws.String("i-correct-2"), Tags: correctTags},
+		{InstanceId: aws.String("i-wrong-value"), Tags: []*ec2.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterID), Value: aws.String("owned")},
+			{Key: aws.String("Name"), Value: aws.String(machine.Name)},
+			{Key: aws.String("UserDefinedTag1"), Value: aws.String("OldValue")},
+		}},
+		{InstanceId: aws.String("i-correct-3"), Tags: correctTags},
+		{InstanceId: aws.String("i-correct-4"), Tags: correctTags},
+		{InstanceId: aws.String("i-failing"), Tags: nil},
+	}
+	userTags := map[string]string{"UserDefinedTag1": "UserDefinedTagValue1"}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockAWSClient := mockaws.NewMockClient(mockCtrl)
+
+	var mu sync.Mutex
+	tagged := map[string]int{}
+	mockAWSClient.EXPECT().CreateTags(gomock.Any()).DoAndReturn(func(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := aws.StringValue(input.Resources[0])
+		tagged[id] = len(input.Tags)
+		if id == "i-failing" {
+			return nil, fmt.Errorf("error")
+		}
+		return &ec2.CreateTagsOutput{}, nil
+	}).Times(3)
+
+	err = correctTagsForInstances(machine, instances, mockAWSClient, userTags)
+	if err == nil || err.Error() != "i-failing: error" {
+		t.Errorf("Expected error %q, got: %v", "i-failing: error", err)
+	}
+
+	expectedTagged := map[string]int{
+		"i-missing-all": 3,
+		"i-wrong-value": 1,
+		"i-failing":     3,
+	}
+	if !reflect.DeepEqual(tagged, expectedTagged) {
+		t.Errorf("Expected tagged instances %v, got: %v", expectedTagged, tagged)
+	}
+	if len(userTags) != 1 {
+		t.Errorf("Expected the user tags to be left untouched, got: %v", userTags)
+	}
+}
+
+func TestTagSpotInstanceRequest(t *testing.T) {
+	tags := map[string]string{"team": "infra", "cost-center": "1234"}
+
+	testCases := []struct {
+		name              string
+		instance          *ec2.Instance
+		expectedCreateTag bool
+	}{
+		{
+			name: "Spot instance",
+			instance: &ec2.Instance{
+				InstanceId:            aws.String(stubInstanceID),
+				SpotInstanceRequestId: aws.String("sir-0123456789"),
+			},
+			expectedCreateTag: true,
+		},
+		{
+			name: "On-demand instance",
+			instance: &ec2.Instance{
+				InstanceId: aws.String(stubInstanceID),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectedCreateTag {
+				mockAWSClient.EXPECT().CreateTags(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{"sir-0123456789"}),
+					Tags: []*ec2.Tag{
+						{Key: aws.String("cost-center"), Value: aws.String("1234")},
+						{Key: aws.String("team"), Value: aws.String("infra")},
+					},
+				}).Return(&ec2.CreateTagsOutput{}, nil)
+			}
+
+			if err := tagSpotInstanceRequest(mockAWSClient, tc.instance, tags); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeEffectiveTags(t *testing.T) {
+	testCases := []struct {
+		name          string
+		labelTags     map[string]string
+		infraTags     map[string]string
+		specTags      []machinev1.TagSpecification
+		expected      map[string]string
+		expectedError bool
+	}{
+		{
+			name:      "No overlap",
+			infraTags: map[string]string{"infra-tag": "infra-value"},
+			specTags:  []machinev1.TagSpecification{{Name: "spec-tag", Value: "spec-value"}},
+			expected:  map[string]string{"infra-tag": "infra-value", "spec-tag": "spec-value"},
+		},
+		{
+			name:      "Spec tag overrides infrastructure tag",
+			infraTags: map[string]string{"team": "infra", "env": "prod"},
+			specTags:  []machinev1.TagSpecification{{Name: "team", Value: "workers"}},
+			expected:  map[string]string{"team": "workers", "env": "prod"},
+		},
+		{
+			name:     "Spec tag repeated with the same value",
+			specTags: []machinev1.TagSpecification{{Name: "team", Value: "workers"}, {Name: "team", Value: "workers"}},
+			expected: map[string]string{"team": "workers"},
+		},
+		{
+			name:          "Spec tag repeated with conflicting values",
+			specTags:      []machinev1.TagSpecification{{Name: "team", Value: "workers"}, {Name: "team", Value: "infra"}},
+			expectedError: true,
+		},
+		{
+			name:          "Spec tag conflicts with the Name tag",
+			specTags:      []machinev1.TagSpecification{{Name: "Name", Value: "my-machine"}},
+			expectedError: true,
+		},
+		{
+			name:          "Spec tag conflicts with the node name tag",
+			specTags:      []machinev1.TagSpecification{{Name: "openshift-machine/node-name", Value: "node"}},
+			expectedError: true,
+		},
+		{
+			name:          "Infrastructure tag conflicts with the cluster tag",
+			infraTags:     map[string]string{"kubernetes.io/cluster/other": "owned"},
+			expectedError: true,
+		},
+		{
+			name:          "Reserved aws prefix",
+			specTags:      []machinev1.TagSpecification{{Name: "aws:cloudformation:stack-name", Value: "stack"}},
+			expectedError: true,
+		},
+		{
+			name:          "Value too long",
+			specTags:      []machinev1.TagSpecification{{Name: "team", Value: strings.Repeat("a", 257)}},
+			expectedError: true,
+		},
+		{
+			name:     "Multi-byte key and value at the limit",
+			specTags: []machinev1.TagSpecification{{Name: strings.Repeat("é", 128), Value: strings.Repeat("é", 256)}},
+			expected: map[string]string{strings.Repeat("é", 128): strings.Repeat("é", 256)},
+		},
+		{
+			name:          "Multi-byte value too long",
+			specTags:      []machinev1.TagSpecification{{Name: "team", Value: strings.Repeat("é", 257)}},
+			expectedError: true,
+		},
+		{
+			name:      "Label tags have the lowest precedence",
+			labelTags: map[string]string{"cost-center/team": "labels", "cost-center/project": "labels"},
+			infraTags: map[string]string{"cost-center/team": "infra"},
+			specTags:  []machinev1.TagSpecification{{Name: "cost-center/project", Value: "spec"}},
+			expected:  map[string]string{"cost-center/team": "infra", "cost-center/project": "spec"},
+		},
+		{
+			name:     "No tags",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := mergeEffectiveTags(tc.labelTags, tc.infraTags, tc.specTags)
+			if tc.expectedError != (err != nil) {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+			if !tc.expectedError && !reflect.DeepEqual(tags, tc.expected) {
+				t.Errorf("Expected tags %v, got %v", tc.expected, tags)
+			}
+		})
+	}
+}
+
+func TestTagsFromMachineLabels(t *testing.T) {
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "machine",
+			Labels: map[string]string{
+				"cost-center.example.com/team":                        "infra",
+				"cost-center.example.com/project":                     "workers",
+				"billing/owner":                                       "me",
+				"machine.openshift.io/cluster-api-machine-role":       "worker",
+				"cost-center.example.com/" + strings.Repeat("a", 128): "too-long",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		prefixes []string
+		expected map[string]string
+	}{
+		{
+			name:     "Matching labels",
+			prefixes: []string{"cost-center.example.com/"},
+			expected: map[string]string{
+				"cost-center.example.com/team":    "infra",
+				"cost-center.example.com/project": "workers",
+			},
+		},
+		{
+			name:     "Several prefixes",
+			prefixes: []string{"cost-center.example.com/team", "billing/"},
+			expected: map[string]string{
+				"cost-center.example.com/team": "infra",
+				"billing/owner":                "me",
+			},
+		},
+		{
+			name:     "No matching labels",
+			prefixes: []string{"example.org/"},
+			expected: map[string]string{},
+		},
+		{
+			name:     "No prefixes",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tags := tagsFromMachineLabels(machine, tc.prefixes); !reflect.DeepEqual(tags, tc.expected) {
+				t.Errorf("Expected tags %v, got %v", tc.expected, tags)
+			}
+		})
+	}
+}
+
+func TestVPCIDForSubnet(t *testing.T) {
+	testCases := []struct {
+		name          string
+		output        *ec2.DescribeSubnetsOutput
+		outputErr     error
+		expectedVPCID string
+		expectedError string
+	}{
+		{
+			name: "Subnet exists",
+			output: &ec2.DescribeSubnetsOutput{
+				Subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-1"), VpcId: aws.String("vpc-1")}},
+			},
+			expectedVPCID: "vpc-1",
+		},
+		{
+			name:          "Subnet does not exist",
+			outputErr:     awserr.New("InvalidSubnetID.NotFound", "The subnet ID 'subnet-1' does not exist", nil),
+			expectedError: "subnet \"subnet-1\" not found",
+		},
+		{
+			name:          "Subnet missing from an empty result",
+			output:        &ec2.DescribeSubnetsOutput{},
+			expectedError: "subnet \"subnet-1\" not found",
+		},
+		{
+			name:          "API error",
+			outputErr:     fmt.Errorf("error"),
+			expectedError: "error describing subnet \"subnet-1\": error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeSubnets(&ec2.DescribeSubnetsInput{
+				SubnetIds: aws.StringSlice([]string{"subnet-1"}),
+			}).Return(tc.output, tc.outputErr)
+
+			vpcID, err := vpcIDForSubnet(mockAWSClient, "subnet-1")
+			if tc.expectedError == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tc.expectedError != "" && (err == nil || err.Error() != tc.expectedError) {
+				t.Errorf("Expected error %q, got: %v", tc.expectedError, err)
+			}
+			if vpcID != tc.expectedVPCID {
+				t.Errorf("Expected VPC ID %q, got %q", tc.expectedVPCID, vpcID)
+			}
+		})
+	}
+}
+
+func TestValidateTagCount(t *testing.T) {
+	userTags := func(count int) map[string]string {
+		tags := make(map[string]string, count)
+		for i := 0; i < count; i++ {
+			tags[fmt.Sprintf("tag-%d", i)] = "value"
+		}
+		return tags
+	}
+	withManagedTags := userTags(48)
+	withManagedTags["Name"] = "machine"
+	withManagedTags["kubernetes.io/cluster/cluster-id"] = "owned"
+
+	testCases := []struct {
+		name          string
+		userTags      map[string]string
+		expectedError string
+	}{
+		{
+			name:     "No user tags",
+			userTags: map[string]string{},
+		},
+		{
+			name:     "At the limit",
+			userTags: userTags(48),
+		},
+		{
+			name:          "One over the limit",
+			userTags:      userTags(49),
+			expectedError: "instance would have 51 tags (49 user defined and 2 managed by the machine controller), 1 over the limit of 50",
+		},
+		{
+			name:          "Several over the limit",
+			userTags:      userTags(55),
+			expectedError: "instance would have 57 tags (55 user defined and 2 managed by the machine controller), 7 over the limit of 50",
+		},
+		{
+			name:     "Managed tags in user tags are not counted",
+			userTags: withManagedTags,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTagCount(tc.userTags)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateExpandedTag(t *testing.T) {
+	testCases := []struct {
+		name          string
+		key           string
+		rawValue      string
+		expanded      string
+		expectedError string
+	}{
+		{
+			name:     "Valid expansion",
+			key:      "owner",
+			rawValue: "{{ .MachineName }}",
+			expanded: "cluster-worker-us-east-1a-abcde",
+		},
+		{
+			name:     "Expansion at the limit",
+			key:      "owner",
+			rawValue: "{{ .MachineName }}",
+			expanded: strings.Repeat("a", 256),
+		},
+		{
+			name:          "Raw value valid but expansion overflows",
+			key:           "owner",
+			rawValue:      "prefix-{{ .MachineName }}",
+			expanded:      strings.Repeat("a", 257),
+			expectedError: "expanded value of tag \"owner\" is 257 characters long, longer than 256 characters",
+		},
+		{
+			name:     "Multi-byte characters are counted once",
+			key:      "owner",
+			rawValue: "{{ .MachineName }}",
+			expanded: strings.Repeat("é", 256),
+		},
+		{
+			name:          "Expansion with control characters",
+			key:           "owner",
+			rawValue:      "{{ .MachineName }}",
+			expanded:      "machine\n",
+			expectedError: "expanded value of tag \"owner\" contains control characters",
+		},
+		{
+			name:          "Expansion with invalid UTF-8",
+			key:           "owner",
+			rawValue:      "{{ .MachineName }}",
+			expanded:      "machine\xff",
+			expectedError: "expanded value of tag \"owner\" is not valid UTF-8",
+		},
+		{
+			name:          "Invalid raw tag",
+			key:           "aws:owner",
+			rawValue:      "{{ .MachineName }}",
+			expanded:      "machine",
+			expectedError: "tag key \"aws:owner\" uses the reserved aws: prefix",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateExpandedTag(tc.key, tc.rawValue, tc.expanded)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestCorrectDuplicateInstanceTags(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	clusterID, _ := getClusterID(machine)
+	tags := map[string]string{"UserDefinedTag1": "UserDefinedTagValue1"}
+
+	instance := &ec2.Instance{InstanceId: aws.String("i-newest")}
+	duplicate := &ec2.Instance{InstanceId: aws.String("i-duplicate")}
+
+	testCases := []struct {
+		name          string
+		instances     []*ec2.Instance
+		expectTagging bool
+		createErr     error
+		skip          bool
+		expectedError bool
+	}{
+		{
+			name:      "Single instance",
+			instances: []*ec2.Instance{instance},
+		},
+		{
+			name:          "Two instances for one machine",
+			instances:     []*ec2.Instance{instance, duplicate},
+			expectTagging: true,
+		},
+		{
+			name:          "Tagging error",
+			instances:     []*ec2.Instance{instance, duplicate},
+			expectTagging: true,
+			createErr:     fmt.Errorf("error"),
+			expectedError: true,
+		},
+		{
+			name:          "Unauthorized tagging of a duplicate fails the reconcile",
+			instances:     []*ec2.Instance{instance, duplicate},
+			expectTagging: true,
+			createErr:     awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil),
+			expectedError: true,
+		},
+		{
+			name:          "Unauthorized tagging of a duplicate is skipped when configured",
+			instances:     []*ec2.Instance{instance, duplicate},
+			expectTagging: true,
+			createErr:     awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil),
+			skip:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectTagging {
+				mockAWSClient.EXPECT().CreateTags(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{"i-duplicate"}),
+					Tags: []*ec2.Tag{
+						{Key: aws.String("Name"), Value: aws.String(machine.Name)},
+						{Key: aws.String("UserDefinedTag1"), Value: aws.String("UserDefinedTagValue1")},
+						{Key: aws.String("kubernetes.io/cluster/" + clusterID), Value: aws.String("owned")},
+					},
+				}).Return(&ec2.CreateTagsOutput{}, tc.createErr)
+			}
+
+			r := newReconciler(&machineScope{
+				awsClient:               mockAWSClient,
+				machine:                 machine,
+				providerStatus:          &machinev1.AWSMachineProviderStatus{},
+				skipUnauthorizedTagging: tc.skip,
+			})
+			err := r.correctDuplicateInstanceTags(instance, tc.instances, tags)
+			if tc.expectedError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+			if unauthorized, ok := tc.createErr.(awserr.Error); ok && unauthorized.Code() == "UnauthorizedOperation" {
+				condition := findProviderCondition(r.providerStatus.Conditions, InstanceTagged)
+				if condition == nil || condition.Reason != InstanceTaggingUnauthorizedConditionReason {
+					t.Errorf("Expected an unauthorized InstanceTagged condition, got %+v", condition)
+				}
+			}
+			if len(tags) != 1 {
+				t.Errorf("Expected the tags to be left untouched, got: %v", tags)
+			}
+		})
+	}
+}
+
+func TestValidateTagPolicy(t *testing.T) {
+	prefixPolicy := regexp.MustCompile(`^acme:`)
+	valuePolicy := regexp.MustCompile(`^[a-z0-9-]+$`)
+
+	testCases := []struct {
+		name           string
+		tags           map[string]string
+		keyPattern     *regexp.Regexp
+		valuePattern   *regexp.Regexp
+		expectedErrors []string
+	}{
+		{
+			name:       "Keys with the required prefix",
+			tags:       map[string]string{"acme:team": "infra", "acme:cost-center": "1234"},
+			keyPattern: prefixPolicy,
+		},
+		{
+			name:       "Keys without the required prefix",
+			tags:       map[string]string{"team": "infra", "acme:cost-center": "1234", "owner": "me"},
+			keyPattern: prefixPolicy,
+			expectedErrors: []string{
+				"tag key \"owner\" does not match the tag policy pattern \"^acme:\"",
+				"tag key \"team\" does not match the tag policy pattern \"^acme:\"",
+			},
+		},
+		{
+			name:         "Keys and values violating the policy",
+			tags:         map[string]string{"team": "Infra Team"},
+			keyPattern:   prefixPolicy,
+			valuePattern: valuePolicy,
+			expectedErrors: []string{
+				"tag key \"team\" does not match the tag policy pattern \"^acme:\"",
+				"value of tag \"team\" does not match the tag policy pattern \"^[a-z0-9-]+$\"",
+			},
+		},
+		{
+			name: "No policy",
+			tags: map[string]string{"team": "Infra Team"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTagPolicy(tc.tags, tc.keyPattern, tc.valuePattern)
+			if len(tc.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected errors %v, got nil", tc.expectedErrors)
+			}
+			errs := []string{}
+			for _, err := range err.(errorutil.Aggregate).Errors() {
+				errs = append(errs, err.Error())
+			}
+			if !reflect.DeepEqual(errs, tc.expectedErrors) {
+				t.Errorf("Expected errors %v, got %v", tc.expectedErrors, errs)
+			}
+		})
+	}
+}
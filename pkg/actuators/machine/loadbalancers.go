@@ -2,7 +2,11 @@ package machine
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	errorutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 
@@ -12,9 +16,70 @@ import (
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 
+	machinev1 "github.com/openshift/api/machine/v1beta1"
 	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
 )
 
+const (
+	// LoadBalancerRegistered reports whether the instance is registered with all the load balancers in the provider spec
+	LoadBalancerRegistered machinev1.ConditionType = "LoadBalancerRegistered"
+
+	// LoadBalancerRegistrationSucceededConditionReason is the condition reason when all registrations succeeded
+	LoadBalancerRegistrationSucceededConditionReason = "LoadBalancerRegistrationSucceeded"
+	// LoadBalancerRegistrationFailedConditionReason is the condition reason when any registration failed
+	LoadBalancerRegistrationFailedConditionReason = "LoadBalancerRegistrationFailed"
+
+	// targetGroupDeregistrationDelayAttribute is the target group attribute holding the connection draining time
+	targetGroupDeregistrationDelayAttribute = "deregistration_delay.timeout_seconds"
+)
+
+// validateClassicLoadBalancers checks that all the named classic load balancers exist.
+// EC2 rejects the whole request when any name is unknown, so in that case each load balancer is
+// described on its own to report every name that does not resolve.
+func validateClassicLoadBalancers(client awsclient.Client, names []string) error {
+	_, err := client.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: aws.StringSlice(names),
+	})
+	if err == nil {
+		return nil
+	}
+	if !isClassicLoadBalancerNotFoundError(err) {
+		klog.Errorf("Failed to describe classic load balancers %v: %v", names, err)
+		return fmt.Errorf("error describing classic load balancers %v: %v", names, err)
+	}
+
+	missing := []string{}
+	errs := []error{}
+	for _, name := range names {
+		_, err := client.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: aws.StringSlice([]string{name}),
+		})
+		if err != nil {
+			if isClassicLoadBalancerNotFoundError(err) {
+				missing = append(missing, name)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("classic load balancers not found: %s", strings.Join(missing, ", ")))
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// isClassicLoadBalancerNotFoundError returns true if the error is the ELB error returned for unknown load balancer names.
+func isClassicLoadBalancerNotFoundError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == elb.ErrCodeAccessPointNotFoundException
+	}
+	return false
+}
+
 func registerWithClassicLoadBalancers(client awsclient.Client, names []string, instance *ec2.Instance) error {
 	klog.V(4).Infof("Updating classic load balancer registration for %q", *instance.InstanceId)
 	elbInstance := &elb.Instance{InstanceId: instance.InstanceId}
@@ -36,28 +101,116 @@ func registerWithClassicLoadBalancers(client awsclient.Client, names []string, i
 	return nil
 }
 
+// enableClassicLoadBalancerAvailabilityZones enables the availability zone of the instance on the named
+// classic load balancers that do not have it enabled yet. Classic load balancers only route traffic to,
+// and accept registrations of, instances in their enabled availability zones.
+func enableClassicLoadBalancerAvailabilityZones(client awsclient.Client, names []string, instance *ec2.Instance) error {
+	if instance.Placement == nil || aws.StringValue(instance.Placement.AvailabilityZone) == "" {
+		return fmt.Errorf("instance %s has no availability zone", aws.StringValue(instance.InstanceId))
+	}
+	availabilityZone := aws.StringValue(instance.Placement.AvailabilityZone)
+
+	result, err := client.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: aws.StringSlice(names),
+	})
+	if err != nil {
+		klog.Errorf("Failed to describe classic load balancers %v: %v", names, err)
+		return fmt.Errorf("error describing classic load balancers %v: %v", names, err)
+	}
+
+	var errs []error
+	for _, loadBalancer := range result.LoadBalancerDescriptions {
+		if stringPointerSliceContains(loadBalancer.AvailabilityZones, availabilityZone) {
+			continue
+		}
+		elbName := aws.StringValue(loadBalancer.LoadBalancerName)
+		klog.Infof("Enabling availability zone %s on classic load balancer %s", availabilityZone, elbName)
+		_, err := client.EnableAvailabilityZonesForLoadBalancer(&elb.EnableAvailabilityZonesForLoadBalancerInput{
+			AvailabilityZones: aws.StringSlice([]string{availabilityZone}),
+			LoadBalancerName:  loadBalancer.LoadBalancerName,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", elbName, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// canRegisterClassicELB reports whether the instance can be registered with the named classic load balancer,
+// and the reason when it cannot: classic load balancers reject instances outside of their VPC or of their enabled
+// availability zones. The scheme of the load balancer is part of the reason as the availability zones of an
+// internet-facing load balancer can only be enabled with public subnets.
+func canRegisterClassicELB(client awsclient.Client, name string, instance *ec2.Instance) (feasible bool, reason string, err error) {
+	if instance == nil || instance.InstanceId == nil {
+		return false, "", fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if instance.Placement == nil || aws.StringValue(instance.Placement.AvailabilityZone) == "" {
+		return false, "", fmt.Errorf("instance %s has no availability zone", *instance.InstanceId)
+	}
+	availabilityZone := aws.StringValue(instance.Placement.AvailabilityZone)
+
+	result, err := client.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		if isClassicLoadBalancerNotFoundError(err) {
+			return false, fmt.Sprintf("classic load balancer %s not found", name), nil
+		}
+		klog.Errorf("Failed to describe classic load balancer %s: %v", name, err)
+		return false, "", fmt.Errorf("error describing classic load balancer %s: %v", name, err)
+	}
+	if len(result.LoadBalancerDescriptions) == 0 {
+		return false, fmt.Sprintf("classic load balancer %s not found", name), nil
+	}
+	loadBalancer := result.LoadBalancerDescriptions[0]
+
+	if vpcID := aws.StringValue(loadBalancer.VPCId); vpcID != "" && vpcID != aws.StringValue(instance.VpcId) {
+		return false, fmt.Sprintf("instance %s is in VPC %s, classic load balancer %s is in VPC %s",
+			*instance.InstanceId, aws.StringValue(instance.VpcId), name, vpcID), nil
+	}
+	if !stringPointerSliceContains(loadBalancer.AvailabilityZones, availabilityZone) {
+		return false, fmt.Sprintf("availability zone %s of instance %s is not enabled on %s classic load balancer %s",
+			availabilityZone, *instance.InstanceId, aws.StringValue(loadBalancer.Scheme), name), nil
+	}
+	return true, "", nil
+}
+
+func stringPointerSliceContains(slice []*string, value string) bool {
+	for _, s := range slice {
+		if aws.StringValue(s) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// registerWithNetworkLoadBalancers registers the instance with the target groups of the named network load balancers.
+// Target groups the instance is already registered with are skipped, so only the missing registrations result in
+// ELBv2RegisterTargets calls. The registered targets are looked up from the target health of each group rather than
+// tracked in the provider status, so registrations changed outside of the machine controller are picked up as well.
 func registerWithNetworkLoadBalancers(client awsclient.Client, names []string, instance *ec2.Instance) error {
 	klog.V(4).Infof("Updating network load balancer registration for %q", *instance.InstanceId)
-	targetGroups, err := gatherLoadBalancerTargetGroups(client, names)
+	targetGroups, classicNames, err := gatherLoadBalancerTargetGroups(client, names)
 	if err != nil {
 		return err
 	}
 
 	errs := []error{}
+	if len(classicNames) > 0 {
+		if err := registerWithClassicLoadBalancers(client, classicNames, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for _, targetGroup := range targetGroups {
 
-		var target *elbv2.TargetDescription
-		switch *targetGroup.TargetType {
-		case elbv2.TargetTypeEnumInstance:
-			target = &elbv2.TargetDescription{
-				Id: instance.InstanceId,
-			}
-			klog.V(4).Infof("Registering instance %q by instance ID to target group: %v", *instance.InstanceId, *targetGroup.TargetGroupArn)
-		case elbv2.TargetTypeEnumIp:
-			target = &elbv2.TargetDescription{
-				Id: instance.PrivateIpAddress,
-			}
-			klog.V(4).Infof("Registering instance %q by IP to target group: %v", *instance.InstanceId, *targetGroup.TargetGroupArn)
+		target, err := targetForTargetGroup(targetGroup, instance)
+		if err != nil {
+			klog.Warningf("Skipping registration for instance %q to target group %q: %v", *instance.InstanceId, *targetGroup.TargetGroupArn, err)
+			continue
 		}
 
 		registeredTargets, err := gatherLoadBalancerTargetGroupRegisteredTargets(client, targetGroup.TargetGroupArn)
@@ -65,6 +218,9 @@ func registerWithNetworkLoadBalancers(client awsclient.Client, names []string, i
 			klog.Errorf("Failed to gather registered targets for target group %q: %v", *targetGroup.TargetGroupArn, err)
 			errs = append(errs, fmt.Errorf("%s: %v", *targetGroup.TargetGroupArn, err))
 		}
+		if err := deregisterStaleTarget(client, targetGroup, target, instance, registeredTargets); err != nil {
+			errs = append(errs, err)
+		}
 		if registeredTargets != nil {
 			if _, ok := registeredTargets[*target.Id]; ok {
 				klog.V(4).Infof("Skipping registration for instance %q to target group %q: Instance already registered", *instance.InstanceId, *targetGroup.TargetGroupArn)
@@ -87,18 +243,81 @@ func registerWithNetworkLoadBalancers(client awsclient.Client, names []string, i
 	return nil
 }
 
+// targetForTargetGroup returns the target the instance is registered as in the target group.
+// An error is returned when the target group cannot accept the instance, so that no register call bound to fail is made:
+// lambda target groups, IP target groups for an instance without a private IPv4 address, and target groups
+// whose protocol version is not supported by their protocol.
+// Gateway load balancer target groups, using the GENEVE protocol, are handled like the others: their appliances
+// are registered by private IP or instance ID depending on the target type, on the port of the target group.
+func targetForTargetGroup(targetGroup *elbv2.TargetGroup, instance *ec2.Instance) (*elbv2.TargetDescription, error) {
+	switch protocolVersion := aws.StringValue(targetGroup.ProtocolVersion); protocolVersion {
+	case "", "HTTP1":
+	case "HTTP2", "GRPC":
+		if protocol := aws.StringValue(targetGroup.Protocol); protocol != elbv2.ProtocolEnumHttp && protocol != elbv2.ProtocolEnumHttps {
+			return nil, fmt.Errorf("protocol version %s is not supported with protocol %s", protocolVersion, protocol)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported protocol version %s", protocolVersion)
+	}
+
+	switch targetType := aws.StringValue(targetGroup.TargetType); targetType {
+	case elbv2.TargetTypeEnumInstance:
+		klog.V(4).Infof("Registering instance %q by instance ID to target group: %v", *instance.InstanceId, *targetGroup.TargetGroupArn)
+		return &elbv2.TargetDescription{Id: instance.InstanceId}, nil
+	case elbv2.TargetTypeEnumIp:
+		if aws.StringValue(instance.PrivateIpAddress) == "" {
+			return nil, fmt.Errorf("instance has no private IP address to register with an IP target group")
+		}
+		klog.V(4).Infof("Registering instance %q by IP to target group: %v", *instance.InstanceId, *targetGroup.TargetGroupArn)
+		return &elbv2.TargetDescription{Id: instance.PrivateIpAddress}, nil
+	default:
+		return nil, fmt.Errorf("target type %q cannot register instances", targetType)
+	}
+}
+
+// deregisterStaleTarget deregisters the instance from the target group when it is registered under the ID the target
+// type of the group does not use anymore: its instance ID in an IP target group, or its IP in an instance target group.
+// This cleans up the registration left behind when the target type of a group is migrated.
+func deregisterStaleTarget(client awsclient.Client, targetGroup *elbv2.TargetGroup, target *elbv2.TargetDescription, instance *ec2.Instance, registeredTargets map[string]struct{}) error {
+	staleID := aws.StringValue(instance.InstanceId)
+	if aws.StringValue(target.Id) == staleID {
+		staleID = aws.StringValue(instance.PrivateIpAddress)
+	}
+	if staleID == "" {
+		return nil
+	}
+	if _, ok := registeredTargets[staleID]; !ok {
+		return nil
+	}
+
+	klog.Infof("Deregistering stale target %q of instance %q from %s target group %q", staleID, *instance.InstanceId,
+		aws.StringValue(targetGroup.TargetType), *targetGroup.TargetGroupArn)
+	_, err := client.ELBv2DeregisterTargets(&elbv2.DeregisterTargetsInput{
+		TargetGroupArn: targetGroup.TargetGroupArn,
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String(staleID)}},
+	})
+	if err != nil {
+		klog.Errorf("Failed to deregister stale target %q from target group %q: %v", staleID, *targetGroup.TargetGroupArn, err)
+		return fmt.Errorf("%s: %v", *targetGroup.TargetGroupArn, err)
+	}
+	return nil
+}
+
 // deregisterNetworkLoadBalancers serves manual instance removal from Network LoadBalancer TargetGroup list
 // for the instances attached by IP. Unlike instance reference, IP attachment should be cleaned manually.
-func deregisterNetworkLoadBalancers(client awsclient.Client, names []string, instance *ec2.Instance) error {
+// It returns the longest deregistration delay across the target groups the instance was removed from, which is
+// how long the load balancers keep draining in-flight connections to the instance.
+func deregisterNetworkLoadBalancers(client awsclient.Client, names []string, instance *ec2.Instance) (time.Duration, error) {
 	if instance.PrivateIpAddress == nil {
 		klog.V(4).Infof("Instance %q does not have private ip, skipping...", *instance.InstanceId)
-		return nil
+		return 0, nil
 	}
 
 	klog.V(4).Infof("Removing network load balancer registration for %q", *instance.InstanceId)
-	targetGroupsOutput, err := gatherLoadBalancerTargetGroups(client, names)
+	// Classic load balancers deregister terminated instances on their own
+	targetGroupsOutput, _, err := gatherLoadBalancerTargetGroups(client, names)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	filteredGroupsByIP := []*elbv2.TargetGroup{}
@@ -108,6 +327,7 @@ func deregisterNetworkLoadBalancers(client awsclient.Client, names []string, ins
 		}
 	}
 
+	var maxDelay time.Duration
 	errs := []error{}
 	for _, targetGroup := range filteredGroupsByIP {
 		klog.V(4).Infof("Unregistering instance %q registered by ip from target group: %v", *instance.InstanceId, *targetGroup.TargetGroupArn)
@@ -129,30 +349,153 @@ func deregisterNetworkLoadBalancers(client awsclient.Client, names []string, ins
 			}
 			klog.Errorf("Failed to unregister instance %q from target group %q: %v", *instance.InstanceId, *targetGroup.TargetGroupArn, err)
 			errs = append(errs, fmt.Errorf("%s: %v", *targetGroup.TargetGroupArn, err))
+			continue
+		}
+
+		delay, err := targetGroupDeregistrationDelay(client, *targetGroup.TargetGroupArn)
+		if err != nil {
+			// The delay is informational only, it must not block the instance removal
+			klog.Warningf("Failed to read deregistration delay of target group %q: %v", *targetGroup.TargetGroupArn, err)
+			continue
+		}
+		klog.Infof("Target group %q will drain connections to instance %q for up to %v", *targetGroup.TargetGroupArn, *instance.InstanceId, delay)
+		if delay > maxDelay {
+			maxDelay = delay
 		}
 	}
+	if len(errs) > 0 {
+		return maxDelay, errorutil.NewAggregate(errs)
+	}
+	return maxDelay, nil
+}
+
+// targetGroupDeregistrationDelay reads the deregistration_delay.timeout_seconds attribute of the given target group.
+func targetGroupDeregistrationDelay(client awsclient.Client, targetGroupArn string) (time.Duration, error) {
+	output, err := client.ELBv2DescribeTargetGroupAttributes(&elbv2.DescribeTargetGroupAttributesInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, attribute := range output.Attributes {
+		if aws.StringValue(attribute.Key) != targetGroupDeregistrationDelayAttribute {
+			continue
+		}
+		seconds, err := strconv.Atoi(aws.StringValue(attribute.Value))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s value %q: %v", targetGroupDeregistrationDelayAttribute, aws.StringValue(attribute.Value), err)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, fmt.Errorf("attribute %s not found", targetGroupDeregistrationDelayAttribute)
+}
+
+// reconcileLoadBalancerMembership moves the instance from the previously configured load balancers to the desired ones:
+// it registers the instance with the load balancers only present in desiredNames and deregisters it from the ones only
+// present in previousNames. Load balancers present in both are left untouched. The previous names have to be tracked by
+// the caller, as load balancers the instance is registered with outside of the provider spec must not be deregistered.
+func reconcileLoadBalancerMembership(client awsclient.Client, desiredNames, previousNames []string, instance *ec2.Instance) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	added := []string{}
+	for _, name := range desiredNames {
+		if !stringSliceContains(previousNames, name) {
+			added = append(added, name)
+		}
+	}
+	removed := []string{}
+	for _, name := range previousNames {
+		if !stringSliceContains(desiredNames, name) {
+			removed = append(removed, name)
+		}
+	}
+
+	errs := []error{}
+	if len(added) > 0 {
+		klog.Infof("Registering instance %q with load balancers %v", *instance.InstanceId, added)
+		if err := registerWithNetworkLoadBalancers(client, added, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(removed) > 0 {
+		klog.Infof("Deregistering instance %q from load balancers %v", *instance.InstanceId, removed)
+		if err := deregisterFromLoadBalancers(client, removed, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	if len(errs) > 0 {
 		return errorutil.NewAggregate(errs)
 	}
 	return nil
 }
 
-func gatherLoadBalancerTargetGroups(client awsclient.Client, names []string) ([]*elbv2.TargetGroup, error) {
-	lbNames := make([]*string, len(names))
-	for i, name := range names {
-		lbNames[i] = aws.String(name)
+// deregisterFromLoadBalancers deregisters the instance from the named classic load balancers and from the target groups
+// of the named network load balancers, whether it is registered by instance ID or by IP.
+// Load balancers or target groups that no longer exist, or the instance is not registered with, are ignored.
+func deregisterFromLoadBalancers(client awsclient.Client, names []string, instance *ec2.Instance) error {
+	targetGroups, classicNames, err := gatherLoadBalancerTargetGroups(client, names)
+	if err != nil {
+		return err
 	}
-	lbsRequest := &elbv2.DescribeLoadBalancersInput{
-		Names: lbNames,
+
+	errs := []error{}
+	for _, elbName := range classicNames {
+		_, err := client.DeregisterInstancesFromLoadBalancer(&elb.DeregisterInstancesFromLoadBalancerInput{
+			Instances:        []*elb.Instance{{InstanceId: instance.InstanceId}},
+			LoadBalancerName: aws.String(elbName),
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok {
+				switch aerr.Code() {
+				case elb.ErrCodeAccessPointNotFoundException, elb.ErrCodeInvalidEndPointException:
+					continue
+				}
+			}
+			klog.Errorf("Failed to deregister instance %q from load balancer %q: %v", *instance.InstanceId, elbName, err)
+			errs = append(errs, fmt.Errorf("%s: %v", elbName, err))
+		}
 	}
-	lbsResponse, err := client.ELBv2DescribeLoadBalancers(lbsRequest)
+
+	for _, targetGroup := range targetGroups {
+		target, err := targetForTargetGroup(targetGroup, instance)
+		if err != nil {
+			klog.V(4).Infof("Skipping deregistration for instance %q from target group %q: %v", *instance.InstanceId, *targetGroup.TargetGroupArn, err)
+			continue
+		}
+
+		_, err = client.ELBv2DeregisterTargets(&elbv2.DeregisterTargetsInput{
+			TargetGroupArn: targetGroup.TargetGroupArn,
+			Targets:        []*elbv2.TargetDescription{target},
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok {
+				switch aerr.Code() {
+				case elbv2.ErrCodeInvalidTargetException, elbv2.ErrCodeTargetGroupNotFoundException:
+					continue
+				}
+			}
+			klog.Errorf("Failed to deregister instance %q from target group %q: %v", *instance.InstanceId, *targetGroup.TargetGroupArn, err)
+			errs = append(errs, fmt.Errorf("%s: %v", *targetGroup.TargetGroupArn, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+func gatherLoadBalancerTargetGroups(client awsclient.Client, names []string) ([]*elbv2.TargetGroup, []string, error) {
+	loadBalancers, classicNames, err := classifyLoadBalancers(client, names)
 	if err != nil {
-		klog.Errorf("Failed to describe load balancers %v: %v", names, err)
-		return nil, err
+		return nil, nil, err
 	}
 	// Use a map for target groups to get unique target group entries across load balancers
 	targetGroups := []*elbv2.TargetGroup{}
-	for _, loadBalancer := range lbsResponse.LoadBalancers {
+	for _, loadBalancer := range loadBalancers {
 		klog.V(4).Infof("Retrieving target groups for load balancer %s", *loadBalancer.LoadBalancerName)
 		targetGroupsInput := &elbv2.DescribeTargetGroupsInput{
 			LoadBalancerArn: loadBalancer.LoadBalancerArn,
@@ -160,12 +503,71 @@ func gatherLoadBalancerTargetGroups(client awsclient.Client, names []string) ([]
 		targetGroupsOutput, err := client.ELBv2DescribeTargetGroups(targetGroupsInput)
 		if err != nil {
 			klog.Errorf("Failed to retrieve load balancer target groups for %q: %v", *loadBalancer.LoadBalancerName, err)
-			return nil, err
+			return nil, nil, err
 		}
 		targetGroups = append(targetGroups, targetGroupsOutput.TargetGroups...)
 	}
 
-	return targetGroups, nil
+	return targetGroups, classicNames, nil
+}
+
+// classifyLoadBalancers describes the named v2 load balancers and returns the names of the classic load balancers
+// among them separately. All names are first described as v2 load balancers in a single call. When some are not
+// found, each name is described on its own and the names unknown to ELBv2 are looked up as classic load balancers.
+func classifyLoadBalancers(client awsclient.Client, names []string) ([]*elbv2.LoadBalancer, []string, error) {
+	lbsResponse, err := client.ELBv2DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		Names: aws.StringSlice(names),
+	})
+	if err == nil {
+		return lbsResponse.LoadBalancers, nil, nil
+	}
+	if !isNetworkLoadBalancerNotFoundError(err) {
+		klog.Errorf("Failed to describe load balancers %v: %v", names, err)
+		return nil, nil, err
+	}
+
+	loadBalancers := []*elbv2.LoadBalancer{}
+	classicNames := []string{}
+	missing := []string{}
+	for _, name := range names {
+		lbsResponse, err := client.ELBv2DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+			Names: aws.StringSlice([]string{name}),
+		})
+		if err == nil {
+			loadBalancers = append(loadBalancers, lbsResponse.LoadBalancers...)
+			continue
+		}
+		if !isNetworkLoadBalancerNotFoundError(err) {
+			klog.Errorf("Failed to describe load balancer %s: %v", name, err)
+			return nil, nil, err
+		}
+
+		_, err = client.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: aws.StringSlice([]string{name}),
+		})
+		if err == nil {
+			klog.V(4).Infof("Load balancer %s is a classic load balancer", name)
+			classicNames = append(classicNames, name)
+			continue
+		}
+		if !isClassicLoadBalancerNotFoundError(err) {
+			klog.Errorf("Failed to describe classic load balancer %s: %v", name, err)
+			return nil, nil, err
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("load balancers not found: %s", strings.Join(missing, ", "))
+	}
+
+	return loadBalancers, classicNames, nil
+}
+
+func isNetworkLoadBalancerNotFoundError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == elbv2.ErrCodeLoadBalancerNotFoundException
+	}
+	return false
 }
 
 // gatherLoadBalancerTargetGroupRegisteredTargets looks for all targets that are registered to a particular targetGroup.
@@ -188,3 +590,36 @@ func gatherLoadBalancerTargetGroupRegisteredTargets(client awsclient.Client, tar
 	}
 	return targetIDs, nil
 }
+
+// lbRegistrationCondition summarizes the results of registerWithClassicLoadBalancers and
+// registerWithNetworkLoadBalancers in a single condition.
+// The registration errors are prefixed with the load balancer name or target group ARN,
+// so the message of a failed condition names everything the instance could not be registered with.
+func lbRegistrationCondition(results ...error) machinev1.AWSMachineProviderCondition {
+	errs := []error{}
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return machinev1.AWSMachineProviderCondition{
+			Type:    LoadBalancerRegistered,
+			Status:  corev1.ConditionTrue,
+			Reason:  LoadBalancerRegistrationSucceededConditionReason,
+			Message: "Instance registered with all load balancers",
+		}
+	}
+
+	messages := []string{}
+	for _, err := range errorutil.Flatten(errorutil.NewAggregate(errs)).Errors() {
+		messages = append(messages, err.Error())
+	}
+	return machinev1.AWSMachineProviderCondition{
+		Type:    LoadBalancerRegistered,
+		Status:  corev1.ConditionFalse,
+		Reason:  LoadBalancerRegistrationFailedConditionReason,
+		Message: fmt.Sprintf("Failed to register instance with load balancers: %s", strings.Join(messages, "; ")),
+	}
+}
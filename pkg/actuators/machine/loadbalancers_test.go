@@ -3,12 +3,18 @@ package machine
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/golang/mock/gomock"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
 	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+	corev1 "k8s.io/api/core/v1"
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
 )
 
 func TestRegisterWithNetworkLoadBalancers(t *testing.T) {
@@ -51,6 +57,319 @@ func TestRegisterWithNetworkLoadBalancers(t *testing.T) {
 	}
 }
 
+func TestRegisterWithNetworkLoadBalancersOnlyRegistersMissingTargets(t *testing.T) {
+	instance := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+	targetHealth := func(ids ...string) *elbv2.DescribeTargetHealthOutput {
+		output := &elbv2.DescribeTargetHealthOutput{}
+		for _, id := range ids {
+			output.TargetHealthDescriptions = append(output.TargetHealthDescriptions, &elbv2.TargetHealthDescription{
+				Target: &elbv2.TargetDescription{Id: aws.String(id)},
+			})
+		}
+		return output
+	}
+
+	cases := []struct {
+		name                   string
+		arn1Targets            *elbv2.DescribeTargetHealthOutput
+		arn2Targets            *elbv2.DescribeTargetHealthOutput
+		expectedRegisteredARNs []string
+	}{
+		{
+			name:        "Already registered with all target groups",
+			arn1Targets: targetHealth("i-other", *instance.InstanceId),
+			arn2Targets: targetHealth(*instance.PrivateIpAddress),
+		},
+		{
+			name:                   "Missing from the IP target group",
+			arn1Targets:            targetHealth(*instance.InstanceId),
+			arn2Targets:            targetHealth("10.0.0.1"),
+			expectedRegisteredARNs: []string{"arn2"},
+		},
+		{
+			name:                   "Not registered with any target group",
+			arn1Targets:            targetHealth(),
+			arn2Targets:            targetHealth(),
+			expectedRegisteredARNs: []string{"arn1", "arn2"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: aws.String("arn1")}).Return(tc.arn1Targets, nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: aws.String("arn2")}).Return(tc.arn2Targets, nil)
+			for _, arn := range tc.expectedRegisteredARNs {
+				mockAWSClient.EXPECT().ELBv2RegisterTargets(registerTargetsMatcher{arn}).Return(&elbv2.RegisterTargetsOutput{}, nil)
+			}
+
+			err := registerWithNetworkLoadBalancers(mockAWSClient, []string{"name1"}, instance)
+			mockCtrl.Finish()
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterWithNetworkLoadBalancersSkipsIncompatibleTargetGroups(t *testing.T) {
+	instance := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+	instanceWithoutIP := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+	instanceWithoutIP.PrivateIpAddress = nil
+
+	targetGroup := func(arn, targetType, protocol, protocolVersion string) *elbv2.TargetGroup {
+		tg := &elbv2.TargetGroup{
+			TargetGroupArn: aws.String(arn),
+			TargetType:     aws.String(targetType),
+			Protocol:       aws.String(protocol),
+		}
+		if protocolVersion != "" {
+			tg.ProtocolVersion = aws.String(protocolVersion)
+		}
+		return tg
+	}
+
+	cases := []struct {
+		name                   string
+		instance               *ec2.Instance
+		targetGroups           []*elbv2.TargetGroup
+		expectedRegisteredARNs []string
+	}{
+		{
+			name:     "Lambda target group",
+			instance: instance,
+			targetGroups: []*elbv2.TargetGroup{
+				targetGroup("arn-instance", elbv2.TargetTypeEnumInstance, elbv2.ProtocolEnumTcp, ""),
+				targetGroup("arn-lambda", elbv2.TargetTypeEnumLambda, "", ""),
+			},
+			expectedRegisteredARNs: []string{"arn-instance"},
+		},
+		{
+			name:     "Protocol version incompatible with the protocol",
+			instance: instance,
+			targetGroups: []*elbv2.TargetGroup{
+				targetGroup("arn-grpc-tcp", elbv2.TargetTypeEnumIp, elbv2.ProtocolEnumTcp, "GRPC"),
+				targetGroup("arn-grpc-https", elbv2.TargetTypeEnumIp, elbv2.ProtocolEnumHttps, "GRPC"),
+				targetGroup("arn-http1", elbv2.TargetTypeEnumInstance, elbv2.ProtocolEnumHttp, "HTTP1"),
+			},
+			expectedRegisteredARNs: []string{"arn-grpc-https", "arn-http1"},
+		},
+		{
+			name:     "Gateway load balancer target groups",
+			instance: instance,
+			targetGroups: []*elbv2.TargetGroup{
+				targetGroup("arn-geneve-ip", elbv2.TargetTypeEnumIp, elbv2.ProtocolEnumGeneve, ""),
+				targetGroup("arn-geneve-instance", elbv2.TargetTypeEnumInstance, elbv2.ProtocolEnumGeneve, ""),
+			},
+			expectedRegisteredARNs: []string{"arn-geneve-ip", "arn-geneve-instance"},
+		},
+		{
+			name:     "Gateway load balancer IP target group for an instance without private IP",
+			instance: instanceWithoutIP,
+			targetGroups: []*elbv2.TargetGroup{
+				targetGroup("arn-geneve-ip", elbv2.TargetTypeEnumIp, elbv2.ProtocolEnumGeneve, ""),
+			},
+		},
+		{
+			name:     "IP target group for an instance without private IP",
+			instance: instanceWithoutIP,
+			targetGroups: []*elbv2.TargetGroup{
+				targetGroup("arn-ip", elbv2.TargetTypeEnumIp, elbv2.ProtocolEnumTcp, ""),
+				targetGroup("arn-instance", elbv2.TargetTypeEnumInstance, elbv2.ProtocolEnumTcp, ""),
+			},
+			expectedRegisteredARNs: []string{"arn-instance"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(&elbv2.DescribeTargetGroupsOutput{TargetGroups: tc.targetGroups}, nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetHealth(gomock.Any()).Return(&elbv2.DescribeTargetHealthOutput{}, nil).Times(len(tc.expectedRegisteredARNs))
+			for _, arn := range tc.expectedRegisteredARNs {
+				mockAWSClient.EXPECT().ELBv2RegisterTargets(registerTargetsMatcher{arn}).Return(&elbv2.RegisterTargetsOutput{}, nil)
+			}
+
+			err := registerWithNetworkLoadBalancers(mockAWSClient, []string{"name1"}, tc.instance)
+			mockCtrl.Finish()
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterWithNetworkLoadBalancersDeregistersStaleTargets(t *testing.T) {
+	instance := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+	targetHealth := func(ids ...string) *elbv2.DescribeTargetHealthOutput {
+		output := &elbv2.DescribeTargetHealthOutput{}
+		for _, id := range ids {
+			output.TargetHealthDescriptions = append(output.TargetHealthDescriptions, &elbv2.TargetHealthDescription{
+				Target: &elbv2.TargetDescription{Id: aws.String(id)},
+			})
+		}
+		return output
+	}
+
+	cases := []struct {
+		name           string
+		targetType     string
+		registered     *elbv2.DescribeTargetHealthOutput
+		staleID        string
+		expectRegister bool
+	}{
+		{
+			name:           "Migrated from instance to ip",
+			targetType:     elbv2.TargetTypeEnumIp,
+			registered:     targetHealth(*instance.InstanceId),
+			staleID:        *instance.InstanceId,
+			expectRegister: true,
+		},
+		{
+			name:           "Migrated from ip to instance",
+			targetType:     elbv2.TargetTypeEnumInstance,
+			registered:     targetHealth(*instance.PrivateIpAddress),
+			staleID:        *instance.PrivateIpAddress,
+			expectRegister: true,
+		},
+		{
+			name:       "Double registration",
+			targetType: elbv2.TargetTypeEnumIp,
+			registered: targetHealth(*instance.InstanceId, *instance.PrivateIpAddress),
+			staleID:    *instance.InstanceId,
+		},
+		{
+			name:       "No stale registration",
+			targetType: elbv2.TargetTypeEnumInstance,
+			registered: targetHealth(*instance.InstanceId),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(&elbv2.DescribeTargetGroupsOutput{
+				TargetGroups: []*elbv2.TargetGroup{{TargetGroupArn: aws.String("arn1"), TargetType: aws.String(tc.targetType)}},
+			}, nil)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetHealth(gomock.Any()).Return(tc.registered, nil)
+			if tc.staleID != "" {
+				mockAWSClient.EXPECT().ELBv2DeregisterTargets(&elbv2.DeregisterTargetsInput{
+					TargetGroupArn: aws.String("arn1"),
+					Targets:        []*elbv2.TargetDescription{{Id: aws.String(tc.staleID)}},
+				}).Return(&elbv2.DeregisterTargetsOutput{}, nil)
+			}
+			if tc.expectRegister {
+				mockAWSClient.EXPECT().ELBv2RegisterTargets(registerTargetsMatcher{"arn1"}).Return(&elbv2.RegisterTargetsOutput{}, nil)
+			}
+
+			err := registerWithNetworkLoadBalancers(mockAWSClient, []string{"name1"}, instance)
+			mockCtrl.Finish()
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGatherLoadBalancerTargetGroupsWithMixedNames(t *testing.T) {
+	describeV2 := func(names ...string) *elbv2.DescribeLoadBalancersInput {
+		return &elbv2.DescribeLoadBalancersInput{Names: aws.StringSlice(names)}
+	}
+	describeClassic := func(name string) *elb.DescribeLoadBalancersInput {
+		return &elb.DescribeLoadBalancersInput{LoadBalancerNames: aws.StringSlice([]string{name})}
+	}
+	v2NotFound := awserr.New(elbv2.ErrCodeLoadBalancerNotFoundException, "One or more load balancers not found", nil)
+	classicNotFound := awserr.New(elb.ErrCodeAccessPointNotFoundException, "Cannot find Load Balancer", nil)
+
+	cases := []struct {
+		name                 string
+		names                []string
+		expectations         func(*mockaws.MockClientMockRecorder)
+		expectedTargetGroups int
+		expectedClassicNames []string
+		expectErr            error
+	}{
+		{
+			name:  "Only network load balancers",
+			names: []string{"nlb1"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ELBv2DescribeLoadBalancers(describeV2("nlb1")).Return(stubDescribeLoadBalancersOutput(), nil)
+				m.ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), nil)
+			},
+			expectedTargetGroups: 2,
+		},
+		{
+			name:  "Mixed classic and network load balancers",
+			names: []string{"clb1", "nlb1", "clb2"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ELBv2DescribeLoadBalancers(describeV2("clb1", "nlb1", "clb2")).Return(nil, v2NotFound)
+				m.ELBv2DescribeLoadBalancers(describeV2("clb1")).Return(nil, v2NotFound)
+				m.DescribeLoadBalancers(describeClassic("clb1")).Return(&elb.DescribeLoadBalancersOutput{}, nil)
+				m.ELBv2DescribeLoadBalancers(describeV2("nlb1")).Return(stubDescribeLoadBalancersOutput(), nil)
+				m.ELBv2DescribeLoadBalancers(describeV2("clb2")).Return(nil, v2NotFound)
+				m.DescribeLoadBalancers(describeClassic("clb2")).Return(&elb.DescribeLoadBalancersOutput{}, nil)
+				m.ELBv2DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{LoadBalancerArn: aws.String("lbarn")}).Return(stubDescribeTargetGroupsOutput(), nil)
+			},
+			expectedTargetGroups: 2,
+			expectedClassicNames: []string{"clb1", "clb2"},
+		},
+		{
+			name:  "Unknown load balancer",
+			names: []string{"nlb1", "missing"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ELBv2DescribeLoadBalancers(describeV2("nlb1", "missing")).Return(nil, v2NotFound)
+				m.ELBv2DescribeLoadBalancers(describeV2("nlb1")).Return(stubDescribeLoadBalancersOutput(), nil)
+				m.ELBv2DescribeLoadBalancers(describeV2("missing")).Return(nil, v2NotFound)
+				m.DescribeLoadBalancers(describeClassic("missing")).Return(nil, classicNotFound)
+			},
+			expectErr: fmt.Errorf("load balancers not found: missing"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+			targetGroups, classicNames, err := gatherLoadBalancerTargetGroups(mockAWSClient, tc.names)
+			mockCtrl.Finish()
+
+			if fmt.Sprintf("%s", err) != fmt.Sprintf("%s", tc.expectErr) {
+				t.Errorf("Unexpected error output: expected '%s', got '%s'", tc.expectErr, err)
+			}
+			if len(targetGroups) != tc.expectedTargetGroups {
+				t.Errorf("Expected %d target groups, got %d", tc.expectedTargetGroups, len(targetGroups))
+			}
+			if fmt.Sprint(classicNames) != fmt.Sprint(tc.expectedClassicNames) {
+				t.Errorf("Expected classic load balancers %v, got %v", tc.expectedClassicNames, classicNames)
+			}
+		})
+	}
+}
+
+type registerTargetsMatcher struct {
+	targetGroupArn string
+}
+
+func (m registerTargetsMatcher) Matches(input interface{}) bool {
+	registerTargetsInput, ok := input.(*elbv2.RegisterTargetsInput)
+	if !ok {
+		return false
+	}
+	return aws.StringValue(registerTargetsInput.TargetGroupArn) == m.targetGroupArn
+}
+
+func (m registerTargetsMatcher) String() string {
+	return fmt.Sprintf("is target group: %s", m.targetGroupArn)
+}
+
 func TestDeregisterNetworkLoadBalancers(t *testing.T) {
 	cases := []struct {
 		name                           string
@@ -61,6 +380,9 @@ func TestDeregisterNetworkLoadBalancers(t *testing.T) {
 		describeTargetGroupsCallTimes  int
 		unregisterTargetErr            error
 		deregisterCallTimes            int
+		attributesErr                  error
+		describeAttributesCallTimes    int
+		expectDelay                    time.Duration
 		expectErr                      error
 	}{
 		{
@@ -73,6 +395,17 @@ func TestDeregisterNetworkLoadBalancers(t *testing.T) {
 			describeLoadBalancersCallTimes: 1,
 			describeTargetGroupsCallTimes:  1,
 			deregisterCallTimes:            1,
+			describeAttributesCallTimes:    1,
+			expectDelay:                    300 * time.Second,
+		},
+		{
+			name:                           "With describe target group attributes error",
+			instance:                       stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true),
+			attributesErr:                  fmt.Errorf("error"),
+			describeLoadBalancersCallTimes: 1,
+			describeTargetGroupsCallTimes:  1,
+			deregisterCallTimes:            1,
+			describeAttributesCallTimes:    1,
 		},
 		{
 			name:                           "With describe lb error",
@@ -119,12 +452,425 @@ func TestDeregisterNetworkLoadBalancers(t *testing.T) {
 			mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), tc.lbErr).Times(tc.describeLoadBalancersCallTimes)
 			mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), tc.targetGroupErr).Times(tc.describeTargetGroupsCallTimes)
 			mockAWSClient.EXPECT().ELBv2DeregisterTargets(gomock.Any()).Return(nil, tc.unregisterTargetErr).Times(tc.deregisterCallTimes)
-			err := deregisterNetworkLoadBalancers(mockAWSClient, []string{"name1", "name2"}, tc.instance)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetGroupAttributes(gomock.Any()).Return(&elbv2.DescribeTargetGroupAttributesOutput{
+				Attributes: []*elbv2.TargetGroupAttribute{{
+					Key:   aws.String("deregistration_delay.timeout_seconds"),
+					Value: aws.String("300"),
+				}},
+			}, tc.attributesErr).Times(tc.describeAttributesCallTimes)
+			delay, err := deregisterNetworkLoadBalancers(mockAWSClient, []string{"name1", "name2"}, tc.instance)
 			mockCtrl.Finish()
 
 			if fmt.Sprintf("%s", err) != fmt.Sprintf("%s", tc.expectErr) {
 				t.Errorf("Unexpeted error output: expected '%s', got '%s'", tc.expectErr, err)
 			}
+			if delay != tc.expectDelay {
+				t.Errorf("Expected deregistration delay: %v, got: %v", tc.expectDelay, delay)
+			}
+		})
+	}
+}
+
+func TestTargetGroupDeregistrationDelay(t *testing.T) {
+	cases := []struct {
+		name        string
+		attributes  []*elbv2.TargetGroupAttribute
+		expectDelay time.Duration
+		expectErr   bool
+	}{
+		{
+			name: "Reads the deregistration delay attribute",
+			attributes: []*elbv2.TargetGroupAttribute{
+				{Key: aws.String("stickiness.enabled"), Value: aws.String("false")},
+				{Key: aws.String("deregistration_delay.timeout_seconds"), Value: aws.String("120")},
+			},
+			expectDelay: 120 * time.Second,
+		},
+		{
+			name: "Zero delay",
+			attributes: []*elbv2.TargetGroupAttribute{
+				{Key: aws.String("deregistration_delay.timeout_seconds"), Value: aws.String("0")},
+			},
+		},
+		{
+			name: "Invalid attribute value",
+			attributes: []*elbv2.TargetGroupAttribute{
+				{Key: aws.String("deregistration_delay.timeout_seconds"), Value: aws.String("forever")},
+			},
+			expectErr: true,
+		},
+		{
+			name:      "Missing attribute",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().ELBv2DescribeTargetGroupAttributes(&elbv2.DescribeTargetGroupAttributesInput{
+				TargetGroupArn: aws.String("arn2"),
+			}).Return(&elbv2.DescribeTargetGroupAttributesOutput{Attributes: tc.attributes}, nil).Times(1)
+
+			delay, err := targetGroupDeregistrationDelay(mockAWSClient, "arn2")
+			mockCtrl.Finish()
+
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.expectErr, err)
+			}
+			if delay != tc.expectDelay {
+				t.Errorf("Expected deregistration delay: %v, got: %v", tc.expectDelay, delay)
+			}
+		})
+	}
+}
+
+func TestLBRegistrationCondition(t *testing.T) {
+	cases := []struct {
+		name              string
+		results           []error
+		expectedCondition machinev1.AWSMachineProviderCondition
+	}{
+		{
+			name:    "All registrations succeeded",
+			results: []error{nil, nil},
+			expectedCondition: machinev1.AWSMachineProviderCondition{
+				Type:    LoadBalancerRegistered,
+				Status:  corev1.ConditionTrue,
+				Reason:  LoadBalancerRegistrationSucceededConditionReason,
+				Message: "Instance registered with all load balancers",
+			},
+		},
+		{
+			name: "Partial failure",
+			results: []error{
+				errorutil.NewAggregate([]error{fmt.Errorf("classic-lb: error")}),
+				errorutil.NewAggregate([]error{fmt.Errorf("arn1: error"), fmt.Errorf("arn2: error")}),
+			},
+			expectedCondition: machinev1.AWSMachineProviderCondition{
+				Type:    LoadBalancerRegistered,
+				Status:  corev1.ConditionFalse,
+				Reason:  LoadBalancerRegistrationFailedConditionReason,
+				Message: "Failed to register instance with load balancers: classic-lb: error; arn1: error; arn2: error",
+			},
+		},
+		{
+			name:    "Network load balancer lookup failed",
+			results: []error{nil, fmt.Errorf("error")},
+			expectedCondition: machinev1.AWSMachineProviderCondition{
+				Type:    LoadBalancerRegistered,
+				Status:  corev1.ConditionFalse,
+				Reason:  LoadBalancerRegistrationFailedConditionReason,
+				Message: "Failed to register instance with load balancers: error",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			condition := lbRegistrationCondition(tc.results...)
+			if condition != tc.expectedCondition {
+				t.Errorf("Expected condition %+v, got %+v", tc.expectedCondition, condition)
+			}
+		})
+	}
+}
+
+func TestValidateClassicLoadBalancers(t *testing.T) {
+	describe := func(names ...string) *elb.DescribeLoadBalancersInput {
+		return &elb.DescribeLoadBalancersInput{LoadBalancerNames: aws.StringSlice(names)}
+	}
+	notFound := awserr.New(elb.ErrCodeAccessPointNotFoundException, "Cannot find Load Balancer", nil)
+
+	cases := []struct {
+		name         string
+		expectations func(*mockaws.MockClientMockRecorder)
+		expectErr    error
+	}{
+		{
+			name: "All load balancers exist",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLoadBalancers(describe("lb1", "lb2", "lb3")).Return(&elb.DescribeLoadBalancersOutput{}, nil)
+			},
+		},
+		{
+			name: "Some load balancers do not exist",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLoadBalancers(describe("lb1", "lb2", "lb3")).Return(nil, notFound)
+				m.DescribeLoadBalancers(describe("lb1")).Return(&elb.DescribeLoadBalancersOutput{}, nil)
+				m.DescribeLoadBalancers(describe("lb2")).Return(nil, notFound)
+				m.DescribeLoadBalancers(describe("lb3")).Return(nil, notFound)
+			},
+			expectErr: fmt.Errorf("classic load balancers not found: lb2, lb3"),
+		},
+		{
+			name: "Describe error",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLoadBalancers(describe("lb1", "lb2", "lb3")).Return(nil, fmt.Errorf("error"))
+			},
+			expectErr: fmt.Errorf("error describing classic load balancers [lb1 lb2 lb3]: error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+			err := validateClassicLoadBalancers(mockAWSClient, []string{"lb1", "lb2", "lb3"})
+			mockCtrl.Finish()
+
+			if fmt.Sprintf("%s", err) != fmt.Sprintf("%s", tc.expectErr) {
+				t.Errorf("Unexpected error output: expected '%s', got '%s'", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestEnableClassicLoadBalancerAvailabilityZones(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		Placement:  &ec2.Placement{AvailabilityZone: aws.String("us-east-1b")},
+	}
+	loadBalancer := func(name string, zones ...string) *elb.LoadBalancerDescription {
+		return &elb.LoadBalancerDescription{LoadBalancerName: aws.String(name), AvailabilityZones: aws.StringSlice(zones)}
+	}
+	enable := func(name string) *elb.EnableAvailabilityZonesForLoadBalancerInput {
+		return &elb.EnableAvailabilityZonesForLoadBalancerInput{
+			AvailabilityZones: aws.StringSlice([]string{"us-east-1b"}),
+			LoadBalancerName:  aws.String(name),
+		}
+	}
+
+	cases := []struct {
+		name         string
+		instance     *ec2.Instance
+		expectations func(*mockaws.MockClientMockRecorder)
+		expectErr    error
+	}{
+		{
+			name:     "Availability zone already enabled",
+			instance: instance,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{
+					LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+						loadBalancer("lb1", "us-east-1a", "us-east-1b"),
+						loadBalancer("lb2", "us-east-1b"),
+					},
+				}, nil)
+			},
+		},
+		{
+			name:     "Availability zone needs enabling",
+			instance: instance,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{
+					LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+						loadBalancer("lb1", "us-east-1a"),
+						loadBalancer("lb2", "us-east-1b"),
+					},
+				}, nil)
+				m.EnableAvailabilityZonesForLoadBalancer(enable("lb1")).Return(&elb.EnableAvailabilityZonesForLoadBalancerOutput{}, nil)
+			},
+		},
+		{
+			name:     "Enable error",
+			instance: instance,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{
+					LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+						loadBalancer("lb1", "us-east-1a"),
+						loadBalancer("lb2", "us-east-1a"),
+					},
+				}, nil)
+				m.EnableAvailabilityZonesForLoadBalancer(enable("lb1")).Return(nil, fmt.Errorf("error"))
+				m.EnableAvailabilityZonesForLoadBalancer(enable("lb2")).Return(&elb.EnableAvailabilityZonesForLoadBalancerOutput{}, nil)
+			},
+			expectErr: errorutil.NewAggregate([]error{fmt.Errorf("lb1: error")}),
+		},
+		{
+			name:         "Instance without availability zone",
+			instance:     &ec2.Instance{InstanceId: aws.String("i-1")},
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+			expectErr:    fmt.Errorf("instance i-1 has no availability zone"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+			err := enableClassicLoadBalancerAvailabilityZones(mockAWSClient, []string{"lb1", "lb2"}, tc.instance)
+			mockCtrl.Finish()
+
+			if fmt.Sprintf("%s", err) != fmt.Sprintf("%s", tc.expectErr) {
+				t.Errorf("Unexpected error output: expected '%s', got '%s'", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCanRegisterClassicELB(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		VpcId:      aws.String("vpc-1"),
+		Placement:  &ec2.Placement{AvailabilityZone: aws.String("us-east-1b")},
+	}
+	loadBalancer := func(vpcID string, zones ...string) *elb.DescribeLoadBalancersOutput {
+		return &elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: []*elb.LoadBalancerDescription{{
+			LoadBalancerName:  aws.String("lb1"),
+			Scheme:            aws.String("internet-facing"),
+			VPCId:             aws.String(vpcID),
+			AvailabilityZones: aws.StringSlice(zones),
+		}}}
+	}
+
+	cases := []struct {
+		name             string
+		output           *elb.DescribeLoadBalancersOutput
+		describeErr      error
+		expectedFeasible bool
+		expectedReason   string
+	}{
+		{
+			name:             "Availability zone enabled",
+			output:           loadBalancer("vpc-1", "us-east-1a", "us-east-1b"),
+			expectedFeasible: true,
+		},
+		{
+			name:           "Availability zone not enabled",
+			output:         loadBalancer("vpc-1", "us-east-1a"),
+			expectedReason: "availability zone us-east-1b of instance i-1 is not enabled on internet-facing classic load balancer lb1",
+		},
+		{
+			name:           "Load balancer in another VPC",
+			output:         loadBalancer("vpc-2", "us-east-1b"),
+			expectedReason: "instance i-1 is in VPC vpc-1, classic load balancer lb1 is in VPC vpc-2",
+		},
+		{
+			name:           "Load balancer not found",
+			describeErr:    awserr.New(elb.ErrCodeAccessPointNotFoundException, "There is no ACTIVE Load Balancer named 'lb1'", nil),
+			expectedReason: "classic load balancer lb1 not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+				LoadBalancerNames: aws.StringSlice([]string{"lb1"}),
+			}).Return(tc.output, tc.describeErr)
+
+			feasible, reason, err := canRegisterClassicELB(mockAWSClient, "lb1", instance)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if feasible != tc.expectedFeasible {
+				t.Errorf("Expected feasible: %v, got: %v", tc.expectedFeasible, feasible)
+			}
+			if reason != tc.expectedReason {
+				t.Errorf("Expected reason %q, got %q", tc.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestReconcileLoadBalancerMembership(t *testing.T) {
+	instance := stubInstance("ami-a9acbbd6", "i-02fcb933c5da7085c", true)
+	v2NotFound := awserr.New(elbv2.ErrCodeLoadBalancerNotFoundException, "One or more load balancers not found", nil)
+
+	expectNetworkLoadBalancer := func(m *mockaws.MockClientMockRecorder, name string) {
+		m.ELBv2DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{Names: aws.StringSlice([]string{name})}).
+			Return(&elbv2.DescribeLoadBalancersOutput{LoadBalancers: []*elbv2.LoadBalancer{
+				{LoadBalancerName: aws.String(name), LoadBalancerArn: aws.String("lb-" + name)},
+			}}, nil)
+		m.ELBv2DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{LoadBalancerArn: aws.String("lb-" + name)}).
+			Return(&elbv2.DescribeTargetGroupsOutput{TargetGroups: []*elbv2.TargetGroup{
+				{TargetGroupArn: aws.String("tg-" + name), TargetType: aws.String(elbv2.TargetTypeEnumInstance)},
+			}}, nil)
+	}
+	expectRegister := func(m *mockaws.MockClientMockRecorder, name string) {
+		expectNetworkLoadBalancer(m, name)
+		m.ELBv2DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: aws.String("tg-" + name)}).
+			Return(&elbv2.DescribeTargetHealthOutput{}, nil)
+		m.ELBv2RegisterTargets(registerTargetsMatcher{"tg-" + name}).Return(&elbv2.RegisterTargetsOutput{}, nil)
+	}
+	expectDeregister := func(m *mockaws.MockClientMockRecorder, name string) {
+		expectNetworkLoadBalancer(m, name)
+		m.ELBv2DeregisterTargets(&elbv2.DeregisterTargetsInput{
+			TargetGroupArn: aws.String("tg-" + name),
+			Targets:        []*elbv2.TargetDescription{{Id: instance.InstanceId}},
+		}).Return(&elbv2.DeregisterTargetsOutput{}, nil)
+	}
+
+	cases := []struct {
+		name          string
+		desiredNames  []string
+		previousNames []string
+		expectations  func(*mockaws.MockClientMockRecorder)
+	}{
+		{
+			name:          "Unchanged load balancers",
+			desiredNames:  []string{"nlb-1"},
+			previousNames: []string{"nlb-1"},
+			expectations:  func(m *mockaws.MockClientMockRecorder) {},
+		},
+		{
+			name:          "Load balancer added",
+			desiredNames:  []string{"nlb-1", "nlb-2"},
+			previousNames: []string{"nlb-1"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				expectRegister(m, "nlb-2")
+			},
+		},
+		{
+			name:          "Load balancer removed",
+			desiredNames:  []string{"nlb-1"},
+			previousNames: []string{"nlb-1", "nlb-2"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				expectDeregister(m, "nlb-2")
+			},
+		},
+		{
+			name:          "Load balancer replaced",
+			desiredNames:  []string{"nlb-2"},
+			previousNames: []string{"nlb-1"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				expectRegister(m, "nlb-2")
+				expectDeregister(m, "nlb-1")
+			},
+		},
+		{
+			name:          "Classic load balancer removed",
+			desiredNames:  []string{},
+			previousNames: []string{"clb-1"},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ELBv2DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{Names: aws.StringSlice([]string{"clb-1"})}).
+					Return(nil, v2NotFound).Times(2)
+				m.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{LoadBalancerNames: aws.StringSlice([]string{"clb-1"})}).
+					Return(&elb.DescribeLoadBalancersOutput{}, nil)
+				m.DeregisterInstancesFromLoadBalancer(&elb.DeregisterInstancesFromLoadBalancerInput{
+					Instances:        []*elb.Instance{{InstanceId: instance.InstanceId}},
+					LoadBalancerName: aws.String("clb-1"),
+				}).Return(&elb.DeregisterInstancesFromLoadBalancerOutput{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			if err := reconcileLoadBalancerMembership(mockAWSClient, tc.desiredNames, tc.previousNames, instance); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
 		})
 	}
 }
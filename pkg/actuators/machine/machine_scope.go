@@ -32,6 +32,14 @@ type machineScopeParams struct {
 	machine *machinev1.Machine
 	// api server controller runtime client for the openshift-config-managed namespace
 	configManagedClient runtimeclient.Client
+	// enable the availability zone of the instance on classic load balancers before registering it
+	enableLoadBalancerAvailabilityZones bool
+	// skip tagging instances the credentials are not authorized to tag instead of failing
+	skipUnauthorizedTagging bool
+	// protect instances attached to an Auto Scaling group from scale in
+	protectInstancesFromScaleIn bool
+	// label key prefixes of the machine labels mirrored as instance tags
+	instanceTagLabelPrefixes []string
 }
 
 type machineScope struct {
@@ -47,6 +55,26 @@ type machineScope struct {
 	originalStatus     machinev1.MachineStatus
 	providerSpec       *machinev1.AWSMachineProviderConfig
 	providerStatus     *machinev1.AWSMachineProviderStatus
+	// enable the availability zone of the instance on classic load balancers before registering it
+	enableLoadBalancerAvailabilityZones bool
+	// skip tagging instances the credentials are not authorized to tag instead of failing
+	skipUnauthorizedTagging bool
+	// protect instances attached to an Auto Scaling group from scale in
+	protectInstancesFromScaleIn bool
+	// label key prefixes of the machine labels mirrored as instance tags
+	instanceTagLabelPrefixes []string
+}
+
+// machineRegion returns the region the AWS client of the machine is built for: the region of the placement,
+// or the region of the placement's availability zone when no region is set.
+func machineRegion(providerSpec *machinev1.AWSMachineProviderConfig) string {
+	if providerSpec.Placement.Region != "" {
+		return providerSpec.Placement.Region
+	}
+	if region, err := regionFromAZ(providerSpec.Placement.AvailabilityZone); err == nil {
+		return region
+	}
+	return ""
 }
 
 func newMachineScope(params machineScopeParams) (*machineScope, error) {
@@ -65,7 +93,7 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		credentialsSecretName = providerSpec.CredentialsSecret.Name
 	}
 
-	awsClient, err := params.awsClientBuilder(params.client, credentialsSecretName, params.machine.Namespace, providerSpec.Placement.Region, params.configManagedClient)
+	awsClient, err := params.awsClientBuilder(params.client, credentialsSecretName, params.machine.Namespace, machineRegion(providerSpec), params.configManagedClient)
 	if err != nil {
 		return nil, machineapierros.InvalidMachineConfiguration("failed to create aws client: %v", err.Error())
 	}
@@ -79,6 +107,11 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		originalStatus:     params.machine.DeepCopy().Status,
 		providerSpec:       providerSpec,
 		providerStatus:     providerStatus,
+
+		enableLoadBalancerAvailabilityZones: params.enableLoadBalancerAvailabilityZones,
+		skipUnauthorizedTagging:             params.skipUnauthorizedTagging,
+		protectInstancesFromScaleIn:         params.protectInstancesFromScaleIn,
+		instanceTagLabelPrefixes:            params.instanceTagLabelPrefixes,
 	}, nil
 }
 
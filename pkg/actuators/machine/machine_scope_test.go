@@ -143,6 +143,55 @@ func TestGetUserData(t *testing.T) {
 	}
 }
 
+func TestNewMachineScopeRegion(t *testing.T) {
+	testCases := []struct {
+		name           string
+		placement      machinev1.Placement
+		expectedRegion string
+	}{
+		{
+			name:           "Region of the placement",
+			placement:      machinev1.Placement{Region: "eu-west-1", AvailabilityZone: "eu-west-1a"},
+			expectedRegion: "eu-west-1",
+		},
+		{
+			name:           "Region derived from the availability zone",
+			placement:      machinev1.Placement{AvailabilityZone: "ap-southeast-2b"},
+			expectedRegion: "ap-southeast-2",
+		},
+		{
+			name:           "Region derived from a local zone",
+			placement:      machinev1.Placement{AvailabilityZone: "us-east-1-bos-1a"},
+			expectedRegion: "us-east-1",
+		},
+		{
+			name: "No placement",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			providerSpec := stubProviderConfig()
+			providerSpec.Placement = tc.placement
+
+			region := "unset"
+			_, err := newMachineScope(machineScopeParams{
+				machine: machineWithSpec(providerSpec),
+				awsClientBuilder: func(client runtimeclient.Client, secretName, namespace, r string, configManagedClient runtimeclient.Client) (awsclient.Client, error) {
+					region = r
+					return nil, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if region != tc.expectedRegion {
+				t.Errorf("Expected client for region %q, got %q", tc.expectedRegion, region)
+			}
+		})
+	}
+}
+
 func TestPatchMachine(t *testing.T) {
 	g := NewWithT(t)
 
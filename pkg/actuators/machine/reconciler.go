@@ -1,6 +1,7 @@
 package machine
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -87,12 +88,15 @@ func (r *Reconciler) create() error {
 		return err
 	}
 
-	instance, err := launchInstance(r.machine, r.providerSpec, userData, r.awsClient, infra)
+	tags, err := r.getEffectiveTags(tagsFromInfrastructure(infra))
+	if err != nil {
+		return fmt.Errorf("failed to get instance tags: %w", err)
+	}
+
+	instance, err := launchInstance(r.machine, r.providerSpec, userData, r.awsClient, tags)
 	if err != nil {
 		klog.Errorf("%s: error creating machine: %v", r.machine.Name, err)
-		conditionFailed := conditionFailed()
-		conditionFailed.Message = err.Error()
-		r.machineScope.setProviderStatus(nil, conditionFailed)
+		r.machineScope.setProviderStatus(nil, launchFailedCondition(err))
 		return fmt.Errorf("failed to launch instance: %w", err)
 	}
 
@@ -106,7 +110,7 @@ func (r *Reconciler) create() error {
 	}
 
 	klog.Infof("Created Machine %v", r.machine.Name)
-	r.machineScope.setProviderStatus(instance, conditionSuccess())
+	r.machineScope.setProviderStatus(instance, conditionSuccess(instance))
 	// DO NOT set addresses on the first pass.
 	// If we set addresses, the machine controller implies that the machine is provisioned.
 	// We remove them here so that we get a chance to requeue when there is a delay in the
@@ -200,7 +204,7 @@ func (r *Reconciler) update() error {
 		klog.Warningf("%s: attempted to update machine but no instances found", r.machine.Name)
 
 		// Update status to clear out machine details.
-		r.machineScope.setProviderStatus(nil, conditionSuccess())
+		r.machineScope.setProviderStatus(nil, conditionSuccess(nil))
 		// This is an unrecoverable error condition.  We should delay to
 		// minimize unnecessary API calls.
 		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterFatalSeconds * time.Second}
@@ -213,7 +217,11 @@ func (r *Reconciler) update() error {
 
 	// Prepare the tag list with infrastructure tags.
 	// These tags will be used to update the EC2 instance tags.
-	tagList, err := r.getTagsFromInfrastructure()
+	infraTags, err := r.getTagsFromInfrastructure()
+	if err != nil {
+		return err
+	}
+	tagList, err := r.getEffectiveTags(infraTags)
 	if err != nil {
 		return err
 	}
@@ -247,17 +255,96 @@ func (r *Reconciler) update() error {
 		return fmt.Errorf("failed to set machine cloud provider specifics: %w", err)
 	}
 
-	if err = correctExistingTags(r.machine, newestInstance, r.awsClient, tagList); err != nil {
+	if err = r.correctDuplicateInstanceTags(newestInstance, existingInstances, tagList); err != nil {
+		return fmt.Errorf("failed to correct tags of duplicate instances: %w", err)
+	}
+
+	if err = r.correctExistingTags(newestInstance, tagList); err != nil {
 		return fmt.Errorf("failed to correct existing instance tags: %w", err)
 	}
 
+	if r.protectInstancesFromScaleIn && runningLen > 0 {
+		if err = protectInstanceFromScaleIn(r.awsClient, *newestInstance.InstanceId); err != nil {
+			return fmt.Errorf("failed to protect instance from scale in: %w", err)
+		}
+	}
+
 	klog.Infof("Updated machine %s", r.machine.Name)
 
-	r.machineScope.setProviderStatus(newestInstance, conditionSuccess())
+	r.machineScope.setProviderStatus(newestInstance, conditionSuccess(newestInstance))
 
 	return r.requeueIfInstancePending(newestInstance)
 }
 
+// correctExistingTags corrects the tags of the instance. Failures to tag the instance because the credentials
+// are not authorized to are recorded in the InstanceTagged condition, and are skipped when configured to.
+// Tag policy violations are recorded in the condition as well, and do not fail the reconcile when the
+// offending tag could be left out.
+func (r *Reconciler) correctExistingTags(instance *ec2.Instance, tags map[string]string) error {
+	err := correctExistingTags(r.machine, instance, r.awsClient, tags)
+	if err == nil {
+		if findProviderCondition(r.providerStatus.Conditions, InstanceTagged) != nil {
+			r.providerStatus.Conditions = setAWSMachineProviderCondition(instanceTaggedCondition(nil), r.providerStatus.Conditions)
+		}
+		return nil
+	}
+	return r.handleTaggingError(err)
+}
+
+// handleTaggingError records tag policy violations and unauthorized tagging in the InstanceTagged condition.
+// It returns nil when the offending tag could be left out, or when unauthorized tagging is skipped.
+func (r *Reconciler) handleTaggingError(err error) error {
+	var violationErr *tagPolicyViolationError
+	if errors.As(err, &violationErr) {
+		r.providerStatus.Conditions = setAWSMachineProviderCondition(instanceTaggedCondition(err), r.providerStatus.Conditions)
+		if violationErr.skipped {
+			return nil
+		}
+		return err
+	}
+
+	var unauthorizedErr *taggingUnauthorizedError
+	if errors.As(err, &unauthorizedErr) {
+		r.providerStatus.Conditions = setAWSMachineProviderCondition(instanceTaggedCondition(err), r.providerStatus.Conditions)
+		if r.skipUnauthorizedTagging {
+			klog.Warningf("%s: Skipping tagging of instance %s: %v", r.machine.Name, unauthorizedErr.instanceID, err)
+			return nil
+		}
+		return err
+	}
+	return err
+}
+
+// correctDuplicateInstanceTags corrects the tags of the instances found for the machine other than the one
+// the machine is reconciled against, so that none is left mistagged while the duplicates are resolved.
+// The tagging failures of each duplicate are handled as the ones of the instance itself.
+func (r *Reconciler) correctDuplicateInstanceTags(instance *ec2.Instance, instances []*ec2.Instance, tags map[string]string) error {
+	duplicates := []*ec2.Instance{}
+	for _, duplicate := range instances {
+		if aws.StringValue(duplicate.InstanceId) != aws.StringValue(instance.InstanceId) {
+			duplicates = append(duplicates, duplicate)
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	klog.Warningf("%s: found %d duplicate instances for machine, correcting their tags", r.machine.Name, len(duplicates))
+	err := correctTagsForInstances(r.machine, duplicates, r.awsClient, tags)
+	aggregate, ok := err.(errorutil.Aggregate)
+	if !ok {
+		return err
+	}
+
+	errs := []error{}
+	for _, instanceErr := range aggregate.Errors() {
+		if err := r.handleTaggingError(instanceErr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errorutil.NewAggregate(errs)
+}
+
 func (r *Reconciler) getTagsFromInfrastructure() (map[string]string, error) {
 	infra := &configv1.Infrastructure{}
 	infraName := client.ObjectKey{Name: awsclient.GlobalInfrastuctureName}
@@ -265,16 +352,32 @@ func (r *Reconciler) getTagsFromInfrastructure() (map[string]string, error) {
 	if err := r.client.Get(r.Context, infraName, infra); err != nil {
 		return nil, fmt.Errorf("error fetching Infrastructure %q: %v", infraName.Name, err)
 	}
+	return tagsFromInfrastructure(infra), nil
+}
+
+// getEffectiveTags returns the user tags of the instance: the tags of the provider spec and of the Infrastructure
+// object, and the machine labels matching the configured prefixes, merged by mergeEffectiveTags.
+func (r *Reconciler) getEffectiveTags(infraTags map[string]string) (map[string]string, error) {
+	labelTags := tagsFromMachineLabels(r.machine, r.instanceTagLabelPrefixes)
+	tags, err := mergeEffectiveTags(labelTags, infraTags, r.providerSpec.Tags)
+	if err != nil {
+		return nil, machinecontroller.InvalidMachineConfiguration("%s: invalid instance tags: %v", r.machine.Name, err)
+	}
+	return tags, nil
+}
+
+// tagsFromInfrastructure returns the resource tags of the Infrastructure object.
+func tagsFromInfrastructure(infra *configv1.Infrastructure) map[string]string {
 	tags := make(map[string]string)
 	resourceTags, ok := fetchInfraResourceTags(infra)
 	if !ok {
-		return tags, nil
+		return tags
 	}
 
 	for _, value := range resourceTags {
 		tags[value.Key] = value.Value
 	}
-	return tags, nil
+	return tags
 }
 
 // exists returns true if machine exists.
@@ -349,8 +452,13 @@ func (r *Reconciler) updateLoadBalancers(instance *ec2.Instance) error {
 
 	var err error
 	if len(classicLoadBalancerNames) > 0 {
-		err := registerWithClassicLoadBalancers(r.awsClient, classicLoadBalancerNames, instance)
-		if err != nil {
+		if err := validateClassicLoadBalancers(r.awsClient, classicLoadBalancerNames); err != nil {
+			klog.Errorf("%s: Failed to validate classic load balancers: %v", r.machine.Name, err)
+			errs = append(errs, err)
+		} else if err := r.enableClassicLoadBalancerAvailabilityZones(classicLoadBalancerNames, instance); err != nil {
+			klog.Errorf("%s: Failed to enable availability zone on classic load balancers: %v", r.machine.Name, err)
+			errs = append(errs, err)
+		} else if err := registerWithClassicLoadBalancers(r.awsClient, classicLoadBalancerNames, instance); err != nil {
 			klog.Errorf("%s: Failed to register classic load balancers: %v", r.machine.Name, err)
 			errs = append(errs, err)
 		}
@@ -368,6 +476,15 @@ func (r *Reconciler) updateLoadBalancers(instance *ec2.Instance) error {
 	return nil
 }
 
+// enableClassicLoadBalancerAvailabilityZones enables the availability zone of the instance on the classic
+// load balancers when the actuator was configured to do so.
+func (r *Reconciler) enableClassicLoadBalancerAvailabilityZones(names []string, instance *ec2.Instance) error {
+	if !r.enableLoadBalancerAvailabilityZones {
+		return nil
+	}
+	return enableClassicLoadBalancerAvailabilityZones(r.awsClient, names, instance)
+}
+
 // updateLoadBalancers adds a given machine instance to the load balancers specified in its provider config
 func (r *Reconciler) removeFromLoadBalancers(instances []*ec2.Instance) error {
 	if len(r.providerSpec.LoadBalancers) == 0 {
@@ -381,16 +498,23 @@ func (r *Reconciler) removeFromLoadBalancers(instances []*ec2.Instance) error {
 		}
 	}
 
+	var maxDelay time.Duration
 	errs := []error{}
 	if len(networkLoadBalancerNames) > 0 {
 		for _, instance := range instances {
-			err := deregisterNetworkLoadBalancers(r.awsClient, networkLoadBalancerNames, instance)
+			delay, err := deregisterNetworkLoadBalancers(r.awsClient, networkLoadBalancerNames, instance)
 			if err != nil {
 				klog.Errorf("%s: Failed to register network load balancers: %v", r.machine.Name, err)
 				errs = append(errs, err)
 			}
+			if delay > maxDelay {
+				maxDelay = delay
+			}
 		}
 	}
+	if maxDelay > 0 {
+		klog.Infof("%s: Network load balancers may keep draining connections for up to %v", r.machine.Name, maxDelay)
+	}
 	if len(errs) > 0 {
 		return errorutil.NewAggregate(errs)
 	}
@@ -442,12 +566,19 @@ func (r *Reconciler) setMachineCloudProviderSpecifics(instance *ec2.Instance) er
 		return fmt.Errorf("error decoding MachineProviderConfig: %w", err)
 	}
 
-	r.machine.Labels[machinecontroller.MachineRegionLabelName] = machineProviderConfig.Placement.Region
+	region := machineRegion(machineProviderConfig)
 
+	// The zone is only known once the instance is launched when the provider spec does not pin it,
+	// e.g. when the subnet is selected by filters. Record the zone the instance was realized in.
 	if instance.Placement != nil {
 		r.machine.Labels[machinecontroller.MachineAZLabelName] = aws.StringValue(instance.Placement.AvailabilityZone)
+		if region == "" {
+			region, _ = regionFromAZ(aws.StringValue(instance.Placement.AvailabilityZone))
+		}
 	}
 
+	r.machine.Labels[machinecontroller.MachineRegionLabelName] = region
+
 	if instance.InstanceType != nil {
 		r.machine.Labels[machinecontroller.MachineInstanceTypeLabelName] = aws.StringValue(instance.InstanceType)
 	}
@@ -485,8 +616,21 @@ func (r *Reconciler) getMachineInstances() ([]*ec2.Instance, error) {
 		i, err := getExistingInstanceByID(*r.providerStatus.InstanceID, r.awsClient)
 		if err != nil {
 			klog.Warningf("%s: Failed to find existing instance by id %s: %v", r.machine.Name, *r.providerStatus.InstanceID, err)
+			if isStaleInstanceIDError(err) {
+				klog.Infof("%s: Clearing stale instance id %s from provider status", r.machine.Name, *r.providerStatus.InstanceID)
+				r.providerStatus.InstanceID = nil
+				r.providerStatus.InstanceState = nil
+			}
 		} else {
 			klog.Infof("%s: Found instance by id: %s", r.machine.Name, *r.providerStatus.InstanceID)
+			// An instance about to be terminated does not need its cluster tag back
+			if r.machine.DeletionTimestamp != nil {
+				return []*ec2.Instance{i}, nil
+			}
+			if err := restoreClusterTag(r.machine, i, r.awsClient); err != nil {
+				// The instance was found, failing to restore its tag must not prevent it from being reconciled
+				klog.Warningf("%s: Failed to restore cluster tag on instance %s: %v", r.machine.Name, *r.providerStatus.InstanceID, err)
+			}
 			return []*ec2.Instance{i}, nil
 		}
 	}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/golang/mock/gomock"
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
@@ -135,6 +137,7 @@ func TestAvailabilityZone(t *testing.T) {
 
 			mockAWSClient.EXPECT().TerminateInstances(gomock.Any()).Return(&ec2.TerminateInstancesOutput{}, nil).AnyTimes()
 			mockAWSClient.EXPECT().RegisterInstancesWithLoadBalancer(gomock.Any()).AnyTimes()
+			mockAWSClient.EXPECT().DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{}, nil).AnyTimes()
 			mockAWSClient.EXPECT().DescribeAvailabilityZones(gomock.Any()).Return(nil, nil).AnyTimes()
 			mockAWSClient.EXPECT().DescribeSubnets(gomock.Any()).Return(&ec2.DescribeSubnetsOutput{}, nil).AnyTimes()
 			mockAWSClient.EXPECT().DescribeVpcs(gomock.Any()).Return(StubDescribeVPCs()).AnyTimes()
@@ -189,6 +192,7 @@ func TestCreate(t *testing.T) {
 	mockAWSClient.EXPECT().TerminateInstances(gomock.Any()).Return(&ec2.TerminateInstancesOutput{}, nil).AnyTimes()
 	mockAWSClient.EXPECT().RunInstances(gomock.Any()).Return(stubReservation("ami-a9acbbd6", instanceID, "192.168.0.10"), nil).AnyTimes()
 	mockAWSClient.EXPECT().RegisterInstancesWithLoadBalancer(gomock.Any()).Return(nil, nil).AnyTimes()
+	mockAWSClient.EXPECT().DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{}, nil).AnyTimes()
 	mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil).AnyTimes()
 	mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), nil).AnyTimes()
 	mockAWSClient.EXPECT().ELBv2DescribeTargetHealth(gomock.Any()).Return(stubDescribeTargetHealthOutput(), nil).AnyTimes()
@@ -681,6 +685,7 @@ func TestUpdate(t *testing.T) {
 				mockAWSClient := mockaws.NewMockClient(mockCtrl)
 				mockAWSClient.EXPECT().DescribeInstances(gomock.Any()).Return(stubDescribeInstancesOutput("test-ami", "test-id", ec2.InstanceStateNameRunning, "1.1.1.1"), nil).AnyTimes()
 				mockAWSClient.EXPECT().RegisterInstancesWithLoadBalancer(gomock.Any()).AnyTimes()
+				mockAWSClient.EXPECT().DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{}, nil).AnyTimes()
 				mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil).AnyTimes()
 				mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), nil).AnyTimes()
 				mockAWSClient.EXPECT().ELBv2RegisterTargets(gomock.Any()).Return(nil, nil).AnyTimes()
@@ -712,6 +717,7 @@ func TestUpdate(t *testing.T) {
 				mockAWSClient := mockaws.NewMockClient(mockCtrl)
 				mockAWSClient.EXPECT().DescribeInstances(gomock.Any()).Return(&ec2.DescribeInstancesOutput{}, nil).AnyTimes()
 				mockAWSClient.EXPECT().RegisterInstancesWithLoadBalancer(gomock.Any()).AnyTimes()
+				mockAWSClient.EXPECT().DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{}, nil).AnyTimes()
 				mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil).AnyTimes()
 				mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), nil).AnyTimes()
 				mockAWSClient.EXPECT().ELBv2RegisterTargets(gomock.Any()).Return(nil, nil).AnyTimes()
@@ -761,6 +767,59 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateMirrorsLabelTags(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("unable to build stub machine: %v", err)
+	}
+	machine.Labels["cost-center.example.com/team"] = "infra"
+	machine.Labels["unrelated.example.com/owner"] = "nobody"
+
+	ctrl := gomock.NewController(t)
+	mockAWSClient := mockaws.NewMockClient(ctrl)
+	mockAWSClient.EXPECT().DescribeInstances(gomock.Any()).Return(stubDescribeInstancesOutput("test-ami", "test-id", ec2.InstanceStateNameRunning, "1.1.1.1"), nil).AnyTimes()
+	mockAWSClient.EXPECT().RegisterInstancesWithLoadBalancer(gomock.Any()).AnyTimes()
+	mockAWSClient.EXPECT().DescribeLoadBalancers(gomock.Any()).Return(&elb.DescribeLoadBalancersOutput{}, nil).AnyTimes()
+	mockAWSClient.EXPECT().ELBv2DescribeLoadBalancers(gomock.Any()).Return(stubDescribeLoadBalancersOutput(), nil).AnyTimes()
+	mockAWSClient.EXPECT().ELBv2DescribeTargetGroups(gomock.Any()).Return(stubDescribeTargetGroupsOutput(), nil).AnyTimes()
+	mockAWSClient.EXPECT().ELBv2RegisterTargets(gomock.Any()).Return(nil, nil).AnyTimes()
+	mockAWSClient.EXPECT().DescribeVpcs(gomock.Any()).Return(StubDescribeVPCs()).AnyTimes()
+	mockAWSClient.EXPECT().ELBv2DescribeTargetHealth(gomock.Any()).Return(stubDescribeTargetHealthOutput(), nil).AnyTimes()
+
+	createdTags := map[string]string{}
+	mockAWSClient.EXPECT().CreateTags(gomock.Any()).DoAndReturn(func(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+		for _, tag := range input.Tags {
+			createdTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return &ec2.CreateTagsOutput{}, nil
+	}).AnyTimes()
+
+	fakeClient := fake.NewFakeClientWithScheme(scheme.Scheme, machine.DeepCopy(), stubAwsCredentialsSecret(), stubUserDataSecret(), stubInfraObject())
+
+	machineScope, err := newMachineScope(machineScopeParams{
+		client:  fakeClient,
+		machine: machine,
+		awsClientBuilder: func(client runtimeclient.Client, secretName, namespace, region string, configManagedClient runtimeclient.Client) (awsclient.Client, error) {
+			return mockAWSClient, nil
+		},
+		instanceTagLabelPrefixes: []string{"cost-center.example.com/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newReconciler(machineScope).update(); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+
+	if value, ok := createdTags["cost-center.example.com/team"]; !ok || value != "infra" {
+		t.Errorf("expected label cost-center.example.com/team=infra to be created as an instance tag, got tags: %v", createdTags)
+	}
+	if _, ok := createdTags["unrelated.example.com/owner"]; ok {
+		t.Errorf("expected label unrelated.example.com/owner not to be mirrored, got tags: %v", createdTags)
+	}
+}
+
 func TestGetMachineInstances(t *testing.T) {
 	clusterID := "aws-actuator-cluster"
 	instanceID := "i-02fa4197109214b46"
@@ -779,6 +838,9 @@ func TestGetMachineInstances(t *testing.T) {
 		providerStatus machinev1.AWSMachineProviderStatus
 		awsClientFunc  func(*gomock.Controller) awsclient.Client
 		exists         bool
+		clearsID       bool
+		hasTagsAfter   bool
+		deleting       bool
 	}{
 		{
 			testcase:       "empty-status-search-by-tag",
@@ -827,6 +889,73 @@ func TestGetMachineInstances(t *testing.T) {
 			},
 			exists: true,
 		},
+		{
+			testcase: "has-status-search-by-id-missing-cluster-tag",
+			providerStatus: machinev1.AWSMachineProviderStatus{
+				InstanceID: aws.String(instanceID),
+			},
+			awsClientFunc: func(ctrl *gomock.Controller) awsclient.Client {
+				mockAWSClient := mockaws.NewMockClient(ctrl)
+
+				output := stubDescribeInstancesOutput(imageID, instanceID, ec2.InstanceStateNameRunning, "192.168.0.10")
+				output.Reservations[0].Instances[0].Tags = []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(machine.Name)}}
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{instanceID}),
+				}).Return(output, nil).Times(1)
+
+				mockAWSClient.EXPECT().CreateTags(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{instanceID}),
+					Tags: []*ec2.Tag{
+						{Key: aws.String(clusterFilterKey(clusterID)), Value: aws.String(clusterFilterValue)},
+					},
+				}).Return(&ec2.CreateTagsOutput{}, nil).Times(1)
+
+				return mockAWSClient
+			},
+			exists:       true,
+			hasTagsAfter: true,
+		},
+		{
+			testcase: "has-status-search-by-id-missing-cluster-tag-deleting",
+			providerStatus: machinev1.AWSMachineProviderStatus{
+				InstanceID: aws.String(instanceID),
+			},
+			awsClientFunc: func(ctrl *gomock.Controller) awsclient.Client {
+				mockAWSClient := mockaws.NewMockClient(ctrl)
+
+				output := stubDescribeInstancesOutput(imageID, instanceID, ec2.InstanceStateNameRunning, "192.168.0.10")
+				output.Reservations[0].Instances[0].Tags = []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(machine.Name)}}
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{instanceID}),
+				}).Return(output, nil).Times(1)
+
+				mockAWSClient.EXPECT().CreateTags(gomock.Any()).Times(0)
+
+				return mockAWSClient
+			},
+			exists:   true,
+			deleting: true,
+		},
+		{
+			testcase: "has-status-search-by-id-missing-cluster-tag-restore-fails",
+			providerStatus: machinev1.AWSMachineProviderStatus{
+				InstanceID: aws.String(instanceID),
+			},
+			awsClientFunc: func(ctrl *gomock.Controller) awsclient.Client {
+				mockAWSClient := mockaws.NewMockClient(ctrl)
+
+				output := stubDescribeInstancesOutput(imageID, instanceID, ec2.InstanceStateNameRunning, "192.168.0.10")
+				output.Reservations[0].Instances[0].Tags = nil
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{instanceID}),
+				}).Return(output, nil).Times(1)
+
+				mockAWSClient.EXPECT().CreateTags(gomock.Any()).Return(nil, awserr.New("UnauthorizedOperation", "not authorized", nil)).Times(1)
+
+				return mockAWSClient
+			},
+			exists: true,
+		},
 		{
 			testcase: "has-status-search-by-id-terminated",
 			providerStatus: machinev1.AWSMachineProviderStatus{
@@ -856,6 +985,60 @@ func TestGetMachineInstances(t *testing.T) {
 					nil,
 				).Times(1).After(first)
 
+				return mockAWSClient
+			},
+			clearsID: true,
+		},
+		{
+			testcase: "has-status-search-by-id-not-found",
+			providerStatus: machinev1.AWSMachineProviderStatus{
+				InstanceID: aws.String(instanceID),
+			},
+			awsClientFunc: func(ctrl *gomock.Controller) awsclient.Client {
+				mockAWSClient := mockaws.NewMockClient(ctrl)
+
+				first := mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{instanceID}),
+				}).Return(&ec2.DescribeInstancesOutput{}, nil).Times(1)
+
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					Filters: []*ec2.Filter{
+						{
+							Name:   awsTagFilter("Name"),
+							Values: aws.StringSlice([]string{machine.Name}),
+						},
+
+						clusterFilter(clusterID),
+					},
+				}).Return(&ec2.DescribeInstancesOutput{}, nil).Times(1).After(first)
+
+				return mockAWSClient
+			},
+			clearsID: true,
+		},
+		{
+			testcase: "has-status-search-by-id-transient-error",
+			providerStatus: machinev1.AWSMachineProviderStatus{
+				InstanceID: aws.String(instanceID),
+			},
+			awsClientFunc: func(ctrl *gomock.Controller) awsclient.Client {
+				mockAWSClient := mockaws.NewMockClient(ctrl)
+
+				first := mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{instanceID}),
+				}).Return(nil, awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)).Times(1)
+
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					Filters: []*ec2.Filter{
+						{
+							Name:   awsTagFilter("Name"),
+							Values: aws.StringSlice([]string{machine.Name}),
+						},
+
+						clusterFilter(clusterID),
+					},
+				}).Return(&ec2.DescribeInstancesOutput{}, nil).Times(1).After(first)
+
 				return mockAWSClient
 			},
 		},
@@ -873,6 +1056,10 @@ func TestGetMachineInstances(t *testing.T) {
 
 			machineCopy := machine.DeepCopy()
 			machineCopy.Status.ProviderStatus = awsStatusRaw
+			if tc.deleting {
+				now := metav1.Now()
+				machineCopy.DeletionTimestamp = &now
+			}
 
 			fakeClient := fake.NewFakeClientWithScheme(scheme.Scheme, machine, awsCredentialsSecret, userDataSecret)
 			mockAWSClient := tc.awsClientFunc(ctrl)
@@ -897,6 +1084,79 @@ func TestGetMachineInstances(t *testing.T) {
 			if tc.exists != (len(instances) > 0) {
 				t.Errorf("Expected instance exists: %t, got instances: %v", tc.exists, instances)
 			}
+			if tc.providerStatus.InstanceID != nil && tc.clearsID != (reconciler.providerStatus.InstanceID == nil) {
+				t.Errorf("Expected instance ID cleared: %t, got: %v", tc.clearsID, aws.StringValue(reconciler.providerStatus.InstanceID))
+			}
+			if tc.hasTagsAfter && !hasTagKey(instances[0].Tags, clusterFilterKey(clusterID)) {
+				t.Errorf("Expected instance to have the cluster tag restored, got tags: %v", instances[0].Tags)
+			}
+		})
+	}
+}
+
+func TestSetMachineCloudProviderSpecificsZone(t *testing.T) {
+	testCases := []struct {
+		name           string
+		placement      machinev1.Placement
+		instance       *ec2.Instance
+		expectedZone   *string
+		expectedRegion string
+	}{
+		{
+			name:      "Zone pinned by the provider spec",
+			placement: machinev1.Placement{Region: "us-east-1", AvailabilityZone: "us-east-1a"},
+			instance: &ec2.Instance{
+				Placement: &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+			},
+			expectedZone:   aws.String("us-east-1a"),
+			expectedRegion: "us-east-1",
+		},
+		{
+			name:      "Zone realized at launch",
+			placement: machinev1.Placement{Region: "us-east-1"},
+			instance: &ec2.Instance{
+				Placement: &ec2.Placement{AvailabilityZone: aws.String("us-east-1c")},
+			},
+			expectedZone:   aws.String("us-east-1c"),
+			expectedRegion: "us-east-1",
+		},
+		{
+			name: "Region derived from the realized zone",
+			instance: &ec2.Instance{
+				Placement: &ec2.Placement{AvailabilityZone: aws.String("eu-west-1b")},
+			},
+			expectedZone:   aws.String("eu-west-1b"),
+			expectedRegion: "eu-west-1",
+		},
+		{
+			name:           "Nil placement",
+			placement:      machinev1.Placement{Region: "us-east-1"},
+			instance:       &ec2.Instance{},
+			expectedRegion: "us-east-1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			providerSpec := stubProviderConfig()
+			providerSpec.Placement = tc.placement
+			r := newReconciler(&machineScope{machine: machineWithSpec(providerSpec)})
+
+			if err := r.setMachineCloudProviderSpecifics(tc.instance); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			zone, ok := r.machine.Labels[machinecontroller.MachineAZLabelName]
+			if tc.expectedZone == nil {
+				if ok {
+					t.Errorf("Expected no zone label, got %q", zone)
+				}
+			} else if zone != *tc.expectedZone {
+				t.Errorf("Expected zone label %q, got %q", *tc.expectedZone, zone)
+			}
+			if region := r.machine.Labels[machinecontroller.MachineRegionLabelName]; region != tc.expectedRegion {
+				t.Errorf("Expected region label %q, got %q", tc.expectedRegion, region)
+			}
 		})
 	}
 }
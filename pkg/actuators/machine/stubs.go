@@ -221,6 +221,12 @@ func stubPCAMI(ami machinev1.AWSResourceReference) *machinev1.AWSMachineProvider
 	return pc
 }
 
+func stubPCAvailabilityZone(availabilityZone string) *machinev1.AWSMachineProviderConfig {
+	pc := stubProviderConfig()
+	pc.Placement.AvailabilityZone = availabilityZone
+	return pc
+}
+
 func stubDedicatedInstanceTenancy() *machinev1.AWSMachineProviderConfig {
 	pc := stubProviderConfig()
 	pc.Placement.Tenancy = machinev1.DedicatedTenancy
@@ -301,6 +307,9 @@ func stubDescribeInstancesOutput(imageID, instanceID string, state string, priva
 						PrivateIpAddress: aws.String(privateIP),
 						PrivateDnsName:   aws.String("privateDNS"),
 						PublicDnsName:    aws.String("publicDNS"),
+						Tags: []*ec2.Tag{
+							{Key: aws.String(clusterFilterKey(stubClusterID)), Value: aws.String(clusterFilterValue)},
+						},
 					},
 				},
 			},
@@ -0,0 +1,307 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// InstanceTagged reports whether the tags of the provider spec could be applied to the instance
+	InstanceTagged machinev1.ConditionType = "InstanceTagged"
+
+	// InstanceTaggingSucceededConditionReason is the condition reason when the tags were applied
+	InstanceTaggingSucceededConditionReason = "InstanceTaggingSucceeded"
+	// InstanceTaggingUnauthorizedConditionReason is the condition reason when the credentials are not allowed to tag the instance
+	InstanceTaggingUnauthorizedConditionReason = "InstanceTaggingUnauthorized"
+	// InstanceTagPolicyViolationConditionReason is the condition reason when a tag violates the tag policy of the organization
+	InstanceTagPolicyViolationConditionReason = "InstanceTagPolicyViolation"
+
+	// nodeNameTagKey is the tag holding the name of the node of the instance. It is managed by the machine controller,
+	// and outside of the reserved kubernetes.io and aws: namespaces.
+	nodeNameTagKey = "openshift-machine/node-name"
+)
+
+// tagPolicyViolationKeyRegexp extracts the offending tag key from TagPolicyViolation error messages, e.g.
+// "The tag policy does not allow the specified value for the following tag key: 'CostCenter'."
+var tagPolicyViolationKeyRegexp = regexp.MustCompile(`tag key: '([^']+)'`)
+
+// taggingUnauthorizedError is returned by correctExistingTags when EC2 rejects CreateTags with UnauthorizedOperation.
+// This is expected when the instance belongs to another account, e.g. a participant account of a VPC shared through RAM.
+type taggingUnauthorizedError struct {
+	instanceID string
+	err        error
+}
+
+func (e *taggingUnauthorizedError) Error() string {
+	return fmt.Sprintf("not authorized to tag instance %s, check that the instance belongs to the account of the machine credentials "+
+		"and that they allow ec2:CreateTags: %v", e.instanceID, e.err)
+}
+
+func (e *taggingUnauthorizedError) Unwrap() error {
+	return e.err
+}
+
+// tagPolicyViolationError is returned by correctExistingTags when EC2 rejects CreateTags with TagPolicyViolation,
+// because a tag does not comply with a tag policy enforced by AWS Organizations.
+type tagPolicyViolationError struct {
+	instanceID string
+	// tagKey is the key of the offending tag, empty when it could not be identified
+	tagKey string
+	// skipped reports that the offending tag was left out and the other tags were applied
+	skipped bool
+	err     error
+}
+
+func (e *tagPolicyViolationError) Error() string {
+	if e.tagKey == "" {
+		return fmt.Sprintf("tags of instance %s violate the tag policy of the organization: %v", e.instanceID, e.err)
+	}
+	message := fmt.Sprintf("tag %q of instance %s violates the tag policy of the organization", e.tagKey, e.instanceID)
+	if e.skipped {
+		message += ", the other tags were applied"
+	}
+	return fmt.Sprintf("%s: %v", message, e.err)
+}
+
+func (e *tagPolicyViolationError) Unwrap() error {
+	return e.err
+}
+
+// tagPolicyViolationKey returns the offending tag key named in a TagPolicyViolation error message, if any.
+func tagPolicyViolationKey(message string) string {
+	if match := tagPolicyViolationKeyRegexp.FindStringSubmatch(message); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// instanceTaggedCondition returns the InstanceTagged condition for the result of correctExistingTags.
+// Only authorization failures and tag policy violations are reported as a failed condition,
+// other errors are returned to the controller.
+func instanceTaggedCondition(err error) machinev1.AWSMachineProviderCondition {
+	var unauthorizedErr *taggingUnauthorizedError
+	if errors.As(err, &unauthorizedErr) {
+		return machinev1.AWSMachineProviderCondition{
+			Type:    InstanceTagged,
+			Status:  corev1.ConditionFalse,
+			Reason:  InstanceTaggingUnauthorizedConditionReason,
+			Message: unauthorizedErr.Error(),
+		}
+	}
+	var violationErr *tagPolicyViolationError
+	if errors.As(err, &violationErr) {
+		return machinev1.AWSMachineProviderCondition{
+			Type:    InstanceTagged,
+			Status:  corev1.ConditionFalse,
+			Reason:  InstanceTagPolicyViolationConditionReason,
+			Message: violationErr.Error(),
+		}
+	}
+	return machinev1.AWSMachineProviderCondition{
+		Type:    InstanceTagged,
+		Status:  corev1.ConditionTrue,
+		Reason:  InstanceTaggingSucceededConditionReason,
+		Message: "Instance tags applied",
+	}
+}
+
+// isClusterOwnedTag returns true if the tag marks the resource as owned by the cluster.
+func isClusterOwnedTag(tag *ec2.Tag, clusterID string) bool {
+	return tag != nil && aws.StringValue(tag.Key) == clusterFilterKey(clusterID) && aws.StringValue(tag.Value) == clusterFilterValue
+}
+
+// instancesMissingClusterTag returns the instances that are not tagged as owned by the cluster.
+func instancesMissingClusterTag(instances []*ec2.Instance, clusterID string) []*ec2.Instance {
+	missing := []*ec2.Instance{}
+	for _, instance := range instances {
+		if instance == nil {
+			continue
+		}
+		owned := false
+		for _, tag := range instance.Tags {
+			if isClusterOwnedTag(tag, clusterID) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			missing = append(missing, instance)
+		}
+	}
+	return missing
+}
+
+// correctAssociatedResourceTags propagates the tags to the resources created alongside the instance:
+// its EBS volumes, its network interfaces and, for spot instances, its spot instance request.
+// All the resources are processed and the resulting errors are aggregated.
+func correctAssociatedResourceTags(machine *machinev1.Machine, instance *ec2.Instance, client awsclient.Client, tags map[string]string) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	klog.V(4).Infof("%s: correcting tags of resources associated with instance %s", machine.Name, *instance.InstanceId)
+	errs := []error{}
+	if err := correctVolumeTags(client, instance, tags); err != nil {
+		errs = append(errs, err)
+	}
+	if err := correctNetworkInterfaceTags(client, instance, tags); err != nil {
+		errs = append(errs, err)
+	}
+	if err := tagSpotInstanceRequest(client, instance, tags); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// correctVolumeTags applies the tags missing from the EBS volumes attached to the instance.
+func correctVolumeTags(client awsclient.Client, instance *ec2.Instance, tags map[string]string) error {
+	volumeIDs := []*string{}
+	for _, blockDeviceMapping := range instance.BlockDeviceMappings {
+		if blockDeviceMapping.Ebs != nil && blockDeviceMapping.Ebs.VolumeId != nil {
+			volumeIDs = append(volumeIDs, blockDeviceMapping.Ebs.VolumeId)
+		}
+	}
+	if len(volumeIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+
+	result, err := client.DescribeVolumes(&ec2.DescribeVolumesInput{VolumeIds: volumeIDs})
+	if err != nil {
+		klog.Errorf("Error describing volumes of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error describing volumes of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	errs := []error{}
+	for _, volume := range result.Volumes {
+		tagsToAdd, _ := computeTagChanges(volume.Tags, tags, nil)
+		if len(tagsToAdd) == 0 {
+			continue
+		}
+		klog.Infof("Updating tags of %s: %v", aws.StringValue(volume.VolumeId), tagsToAdd)
+		if err := createTagsWithRetry(client, &ec2.CreateTagsInput{Resources: []*string{volume.VolumeId}, Tags: tagsToAdd}); err != nil {
+			klog.Errorf("Error tagging %s: %v", aws.StringValue(volume.VolumeId), err)
+			errs = append(errs, fmt.Errorf("error tagging %s: %v", aws.StringValue(volume.VolumeId), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// correctNetworkInterfaceTags applies the tags missing from the network interfaces attached to the instance.
+func correctNetworkInterfaceTags(client awsclient.Client, instance *ec2.Instance, tags map[string]string) error {
+	networkInterfaceIDs := []*string{}
+	for _, networkInterface := range instance.NetworkInterfaces {
+		if networkInterface.NetworkInterfaceId != nil {
+			networkInterfaceIDs = append(networkInterfaceIDs, networkInterface.NetworkInterfaceId)
+		}
+	}
+	if len(networkInterfaceIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+
+	result, err := client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: networkInterfaceIDs})
+	if err != nil {
+		klog.Errorf("Error describing network interfaces of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error describing network interfaces of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	errs := []error{}
+	for _, networkInterface := range result.NetworkInterfaces {
+		tagsToAdd, _ := computeTagChanges(networkInterface.TagSet, tags, nil)
+		if len(tagsToAdd) == 0 {
+			continue
+		}
+		klog.Infof("Updating tags of %s: %v", aws.StringValue(networkInterface.NetworkInterfaceId), tagsToAdd)
+		if err := createTagsWithRetry(client, &ec2.CreateTagsInput{Resources: []*string{networkInterface.NetworkInterfaceId}, Tags: tagsToAdd}); err != nil {
+			klog.Errorf("Error tagging %s: %v", aws.StringValue(networkInterface.NetworkInterfaceId), err)
+			errs = append(errs, fmt.Errorf("error tagging %s: %v", aws.StringValue(networkInterface.NetworkInterfaceId), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// tagInstanceDedicatedHost applies the tags to the dedicated host the instance runs on, if any.
+func tagInstanceDedicatedHost(client awsclient.Client, instance *ec2.Instance, tags map[string]string) error {
+	if instance == nil || instance.Placement == nil {
+		return nil
+	}
+	return tagDedicatedHost(client, aws.StringValue(instance.Placement.HostId), tags)
+}
+
+// tagDedicatedHost applies the tags, usually the cluster and Name tags, to the dedicated host
+// so that the host shows up alongside its instances in cost reports.
+// An empty host ID means the instance does not run on a dedicated host and is skipped.
+func tagDedicatedHost(client awsclient.Client, hostID string, tags map[string]string) error {
+	if hostID == "" || len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tagList := make([]*ec2.Tag, 0, len(keys))
+	for _, key := range keys {
+		tagList = append(tagList, &ec2.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+
+	klog.Infof("Updating tags of dedicated host %s: %v", hostID, keys)
+	_, err := client.CreateTags(&ec2.CreateTagsInput{
+		Resources: aws.StringSlice([]string{hostID}),
+		Tags:      tagList,
+	})
+	if err != nil {
+		klog.Errorf("Error tagging dedicated host %s: %v", hostID, err)
+		return fmt.Errorf("error tagging dedicated host %s: %v", hostID, err)
+	}
+	return nil
+}
+
+// tagInstanceWithNodeName tags the instance with the name of its node, so that instances can be correlated
+// with nodes once they joined the cluster. Nothing is sent when the tag is already up to date.
+func tagInstanceWithNodeName(client awsclient.Client, instance *ec2.Instance, nodeName string) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if nodeName == "" {
+		return fmt.Errorf("node name of instance %s must not be empty", *instance.InstanceId)
+	}
+	if len(nodeName) > maxTagValueLength {
+		return fmt.Errorf("node name %q of instance %s is longer than %d characters", nodeName, *instance.InstanceId, maxTagValueLength)
+	}
+
+	tagsToAdd, _ := computeTagChanges(instance.Tags, map[string]string{nodeNameTagKey: nodeName}, nil)
+	if len(tagsToAdd) == 0 {
+		return nil
+	}
+
+	klog.Infof("Tagging instance %s with node name %s", *instance.InstanceId, nodeName)
+	if err := applyTagChanges(client, instance, tagsToAdd, nil); err != nil {
+		klog.Errorf("Error tagging instance %s with node name: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error tagging instance %s with node name: %v", *instance.InstanceId, err)
+	}
+	return nil
+}
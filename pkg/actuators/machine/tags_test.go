@@ -0,0 +1,263 @@
+package machine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestCorrectAssociatedResourceTags(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+
+	instance := &ec2.Instance{
+		InstanceId:            aws.String(stubInstanceID),
+		SpotInstanceRequestId: aws.String("sir-1"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")}},
+		},
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{NetworkInterfaceId: aws.String("eni-1")},
+		},
+	}
+	tags := map[string]string{"team": "infra"}
+	teamTag := []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("infra")}}
+	createTags := func(resourceID string) *ec2.CreateTagsInput {
+		return &ec2.CreateTagsInput{Resources: aws.StringSlice([]string{resourceID}), Tags: teamTag}
+	}
+
+	testCases := []struct {
+		name           string
+		expectations   func(*mockaws.MockClientMockRecorder)
+		expectedErrors int
+	}{
+		{
+			name: "All resources are tagged",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeVolumes(&ec2.DescribeVolumesInput{VolumeIds: aws.StringSlice([]string{"vol-1"})}).
+					Return(&ec2.DescribeVolumesOutput{Volumes: []*ec2.Volume{{VolumeId: aws.String("vol-1")}}}, nil)
+				m.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: aws.StringSlice([]string{"eni-1"})}).
+					Return(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []*ec2.NetworkInterface{{NetworkInterfaceId: aws.String("eni-1")}}}, nil)
+				m.CreateTags(createTags("vol-1")).Return(&ec2.CreateTagsOutput{}, nil)
+				m.CreateTags(createTags("eni-1")).Return(&ec2.CreateTagsOutput{}, nil)
+				m.CreateTags(createTags("sir-1")).Return(&ec2.CreateTagsOutput{}, nil)
+			},
+		},
+		{
+			name: "Already tagged resources are skipped",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{Volumes: []*ec2.Volume{{VolumeId: aws.String("vol-1"), Tags: teamTag}}}, nil)
+				m.DescribeNetworkInterfaces(gomock.Any()).
+					Return(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []*ec2.NetworkInterface{{NetworkInterfaceId: aws.String("eni-1"), TagSet: teamTag}}}, nil)
+				m.CreateTags(createTags("sir-1")).Return(&ec2.CreateTagsOutput{}, nil)
+			},
+		},
+		{
+			name: "Values differing only in surrounding whitespace are not retagged",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				paddedTag := []*ec2.Tag{{Key: aws.String("team"), Value: aws.String(" infra ")}}
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{Volumes: []*ec2.Volume{{VolumeId: aws.String("vol-1"), Tags: paddedTag}}}, nil)
+				m.DescribeNetworkInterfaces(gomock.Any()).
+					Return(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []*ec2.NetworkInterface{{NetworkInterfaceId: aws.String("eni-1"), TagSet: paddedTag}}}, nil)
+				m.CreateTags(createTags("sir-1")).Return(&ec2.CreateTagsOutput{}, nil)
+			},
+		},
+		{
+			name: "Errors are aggregated",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeVolumes(gomock.Any()).Return(nil, fmt.Errorf("error"))
+				m.DescribeNetworkInterfaces(gomock.Any()).
+					Return(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []*ec2.NetworkInterface{{NetworkInterfaceId: aws.String("eni-1")}}}, nil)
+				m.CreateTags(createTags("eni-1")).Return(nil, fmt.Errorf("error"))
+				m.CreateTags(createTags("sir-1")).Return(nil, fmt.Errorf("error"))
+			},
+			expectedErrors: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := correctAssociatedResourceTags(machine, instance, mockAWSClient, tags)
+			if tc.expectedErrors == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected %d errors, got nil", tc.expectedErrors)
+			}
+			if errs := errorutil.Flatten(err.(errorutil.Aggregate)).Errors(); len(errs) != tc.expectedErrors {
+				t.Errorf("Expected %d errors, got %d: %v", tc.expectedErrors, len(errs), err)
+			}
+		})
+	}
+}
+
+func TestInstancesMissingClusterTag(t *testing.T) {
+	clusterID := "cluster-id"
+	instance := func(id string, tags ...*ec2.Tag) *ec2.Instance {
+		return &ec2.Instance{InstanceId: aws.String(id), Tags: tags}
+	}
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+
+	instances := []*ec2.Instance{
+		instance("i-owned", tag("Name", "machine"), tag("kubernetes.io/cluster/cluster-id", "owned")),
+		instance("i-untagged"),
+		instance("i-shared", tag("kubernetes.io/cluster/cluster-id", "shared")),
+		instance("i-other-cluster", tag("kubernetes.io/cluster/other", "owned")),
+	}
+
+	missing := instancesMissingClusterTag(instances, clusterID)
+	ids := []string{}
+	for _, instance := range missing {
+		ids = append(ids, aws.StringValue(instance.InstanceId))
+	}
+	expected := []string{"i-untagged", "i-shared", "i-other-cluster"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected instances %v, got %v", expected, ids)
+	}
+}
+
+func TestTagInstanceDedicatedHost(t *testing.T) {
+	tags := map[string]string{"kubernetes.io/cluster/cluster-id": "owned", "Name": "machine"}
+
+	testCases := []struct {
+		name        string
+		instance    *ec2.Instance
+		expectTags  bool
+		createErr   error
+		expectError bool
+	}{
+		{
+			name: "Instance on a dedicated host",
+			instance: &ec2.Instance{
+				InstanceId: aws.String(stubInstanceID),
+				Placement:  &ec2.Placement{HostId: aws.String("h-0123456789")},
+			},
+			expectTags: true,
+		},
+		{
+			name: "Instance without dedicated host",
+			instance: &ec2.Instance{
+				InstanceId: aws.String(stubInstanceID),
+				Placement:  &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+			},
+		},
+		{
+			name: "Tagging error",
+			instance: &ec2.Instance{
+				InstanceId: aws.String(stubInstanceID),
+				Placement:  &ec2.Placement{HostId: aws.String("h-0123456789")},
+			},
+			expectTags:  true,
+			createErr:   fmt.Errorf("error"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectTags {
+				mockAWSClient.EXPECT().CreateTags(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{"h-0123456789"}),
+					Tags: []*ec2.Tag{
+						{Key: aws.String("Name"), Value: aws.String("machine")},
+						{Key: aws.String("kubernetes.io/cluster/cluster-id"), Value: aws.String("owned")},
+					},
+				}).Return(&ec2.CreateTagsOutput{}, tc.createErr)
+			}
+
+			err := tagInstanceDedicatedHost(mockAWSClient, tc.instance, tags)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestTagInstanceWithNodeName(t *testing.T) {
+	nodeNameTag := func(nodeName string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(nodeNameTagKey), Value: aws.String(nodeName)}
+	}
+	instance := func(tags ...*ec2.Tag) *ec2.Instance {
+		return &ec2.Instance{InstanceId: aws.String(stubInstanceID), Tags: tags}
+	}
+
+	testCases := []struct {
+		name        string
+		instance    *ec2.Instance
+		nodeName    string
+		expectTags  bool
+		expectError bool
+	}{
+		{
+			name:       "Instance without node name tag",
+			instance:   instance(),
+			nodeName:   "ip-10-0-1-2.ec2.internal",
+			expectTags: true,
+		},
+		{
+			name:       "Outdated node name tag",
+			instance:   instance(nodeNameTag("ip-10-0-1-3.ec2.internal")),
+			nodeName:   "ip-10-0-1-2.ec2.internal",
+			expectTags: true,
+		},
+		{
+			name:     "Node name tag up to date",
+			instance: instance(nodeNameTag("ip-10-0-1-2.ec2.internal")),
+			nodeName: "ip-10-0-1-2.ec2.internal",
+		},
+		{
+			name:        "Empty node name",
+			instance:    instance(),
+			expectError: true,
+		},
+		{
+			name:        "Node name too long",
+			instance:    instance(),
+			nodeName:    strings.Repeat("a", 257),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectTags {
+				mockAWSClient.EXPECT().CreateTags(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{stubInstanceID}),
+					Tags:      []*ec2.Tag{nodeNameTag(tc.nodeName)},
+				}).Return(&ec2.CreateTagsOutput{}, nil)
+			}
+
+			err := tagInstanceWithNodeName(mockAWSClient, tc.instance, tc.nodeName)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -17,26 +17,65 @@ limitations under the License.
 package machine
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	errorutil "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 )
 
-// upstreamMachineClusterIDLabel is the label that a machine must have to identify the cluster to which it belongs
-const upstreamMachineClusterIDLabel = "sigs.k8s.io/cluster-api-cluster"
+const (
+	// upstreamMachineClusterIDLabel is the label that a machine must have to identify the cluster to which it belongs
+	upstreamMachineClusterIDLabel = "sigs.k8s.io/cluster-api-cluster"
+
+	// QuotaExceededConditionReason is the reason of the failed MachineCreation condition
+	// when the instance could not be launched because an account quota is exhausted
+	QuotaExceededConditionReason = "QuotaExceeded"
+
+	// providerIDPrefix is the scheme of the providerID set on machines and nodes
+	providerIDPrefix = "aws://"
+
+	// maxConcurrentTagCorrections is the maximum number of instances correctTagsForInstances updates in parallel
+	maxConcurrentTagCorrections = 5
+
+	// ec2InstanceNotFoundErrorCode is the error code EC2 returns when a requested instance ID does not exist
+	ec2InstanceNotFoundErrorCode = "InvalidInstanceID.NotFound"
+
+	// ec2TagLimitExceededErrorCode is returned by EC2 when a resource would have more than maxInstanceTags tags
+	ec2TagLimitExceededErrorCode = "TagLimitExceeded"
+	maxInstanceTags              = 50
+
+	// ec2UnauthorizedOperationErrorCode is returned by EC2 when the credentials are not allowed to perform the operation
+	ec2UnauthorizedOperationErrorCode = "UnauthorizedOperation"
+
+	// ec2TagPolicyViolationErrorCode is returned by EC2 when a tag does not comply with an enforced Organizations tag policy
+	ec2TagPolicyViolationErrorCode = "TagPolicyViolation"
+)
 
 // existingInstanceStates returns the list of states an EC2 instance can be in
 // while being considered "existing", i.e. mostly anything but "Terminated".
@@ -61,6 +100,21 @@ func getRunningFromInstances(instances []*ec2.Instance) []*ec2.Instance {
 	return runningInstances
 }
 
+// detectStuckPending returns the pending instances launched longer than the threshold ago.
+// Instances without launch time are excluded as their age is unknown.
+func detectStuckPending(instances []*ec2.Instance, threshold time.Duration) []*ec2.Instance {
+	var stuckInstances []*ec2.Instance
+	for _, instance := range instances {
+		if instance == nil || instance.State == nil || aws.StringValue(instance.State.Name) != ec2.InstanceStateNamePending {
+			continue
+		}
+		if instance.LaunchTime != nil && time.Since(*instance.LaunchTime) > threshold {
+			stuckInstances = append(stuckInstances, instance)
+		}
+	}
+	return stuckInstances
+}
+
 // getStoppedInstances returns all stopped instances that have a tag matching our machine name,
 // and cluster ID.
 func getStoppedInstances(machine *machinev1.Machine, client awsclient.Client) ([]*ec2.Instance, error) {
@@ -77,6 +131,13 @@ func getExistingInstanceByID(id string, client awsclient.Client) (*ec2.Instance,
 	return getInstanceByID(id, client, existingInstanceStates())
 }
 
+// isStaleInstanceIDError returns true if looking up the instance by the ID stored in the provider status failed
+// because the instance no longer exists or is terminated, in which case the stored ID should be cleared.
+// Other errors, e.g. throttling, may be transient and leave the stored ID untouched.
+func isStaleInstanceIDError(err error) bool {
+	return errors.Is(err, ErrInstanceNotFound)
+}
+
 func instanceHasAllowedState(instance *ec2.Instance, instanceStateFilter []*string) error {
 	if instance.InstanceId == nil {
 		return fmt.Errorf("instance has nil ID")
@@ -116,10 +177,17 @@ func getInstanceByID(id string, client awsclient.Client, instanceStateFilter []*
 
 	result, err := client.DescribeInstances(request)
 	if err != nil {
+		if isInstanceNotFoundError(err) {
+			return nil, fmt.Errorf("%w: instance-id %s", ErrInstanceNotFound, id)
+		}
 		return nil, err
 	}
 
-	if len(result.Reservations) != 1 {
+	switch len(result.Reservations) {
+	case 0:
+		return nil, fmt.Errorf("%w: found 0 reservations for instance-id %s", ErrInstanceNotFound, id)
+	case 1:
+	default:
 		return nil, fmt.Errorf("found %d reservations for instance-id %s", len(result.Reservations), id)
 	}
 
@@ -131,11 +199,188 @@ func getInstanceByID(id string, client awsclient.Client, instanceStateFilter []*
 
 	instance := reservation.Instances[0]
 
-	return instance, instanceHasAllowedState(instance, instanceStateFilter)
+	if err := instanceHasAllowedState(instance, instanceStateFilter); err != nil {
+		// EC2 keeps describing terminated instances for a while after they are gone,
+		// they are reported as not found rather than as being in an unexpected state.
+		if instance.State != nil {
+			switch aws.StringValue(instance.State.Name) {
+			case ec2.InstanceStateNameTerminated, ec2.InstanceStateNameShuttingDown:
+				return instance, fmt.Errorf("%w: %v", ErrInstanceNotFound, err)
+			}
+		}
+		return instance, err
+	}
+	return instance, nil
+}
+
+// getInstanceWithState returns the instance with the given ID whatever its state is,
+// along with whether the instance is terminal, i.e. shutting down or terminated.
+func getInstanceWithState(id string, client awsclient.Client) (*ec2.Instance, bool, error) {
+	instance, err := getInstanceByID(id, client, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch aws.StringValue(instance.State.Name) {
+	case ec2.InstanceStateNameShuttingDown, ec2.InstanceStateNameTerminated:
+		return instance, true, nil
+	default:
+		return instance, false, nil
+	}
+}
+
+// parseProviderID returns the availability zone and instance ID encoded in a providerID
+// of the form aws:///<availability-zone>/<instance-id>. The availability zone may be empty.
+func parseProviderID(providerID string) (string, string, error) {
+	if !strings.HasPrefix(providerID, providerIDPrefix) {
+		return "", "", fmt.Errorf("providerID %q does not start with %q", providerID, providerIDPrefix)
+	}
+
+	parts := strings.Split(strings.TrimLeft(strings.TrimPrefix(providerID, providerIDPrefix), "/"), "/")
+	var availabilityZone, instanceID string
+	switch len(parts) {
+	case 1:
+		instanceID = parts[0]
+	case 2:
+		availabilityZone, instanceID = parts[0], parts[1]
+	default:
+		return "", "", fmt.Errorf("providerID %q is not of the form %s/<availability-zone>/<instance-id>", providerID, providerIDPrefix)
+	}
+	if !strings.HasPrefix(instanceID, "i-") {
+		return "", "", fmt.Errorf("providerID %q does not contain a valid instance ID", providerID)
+	}
+
+	return availabilityZone, instanceID, nil
+}
+
+// getInstanceByProviderID returns the instance referenced by the providerID of a machine or node.
+func getInstanceByProviderID(providerID string, client awsclient.Client, instanceStateFilter []*string) (*ec2.Instance, error) {
+	_, instanceID, err := parseProviderID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := getInstanceByID(instanceID, client, instanceStateFilter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting instance for providerID %q: %w", providerID, err)
+	}
+	return instance, nil
+}
+
+// getInstanceByPrivateDNS returns the instance with the given private DNS name if it exists.
+// Instances that are not in one of the allowed states are ignored.
+func getInstanceByPrivateDNS(client awsclient.Client, privateDNS string, instanceStateFilter []*string) (*ec2.Instance, error) {
+	if privateDNS == "" {
+		return nil, fmt.Errorf("private DNS name not specified")
+	}
+
+	request := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("private-dns-name"),
+				Values: aws.StringSlice([]string{privateDNS}),
+			},
+		},
+	}
+
+	result, err := client.DescribeInstances(request)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []*ec2.Instance{}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			if err := instanceHasAllowedState(instance, instanceStateFilter); err != nil {
+				klog.Errorf("Excluding instance matching private DNS name %s: %v", privateDNS, err)
+				continue
+			}
+			instances = append(instances, instance)
+		}
+	}
+
+	switch len(instances) {
+	case 0:
+		return nil, fmt.Errorf("%w: private-dns-name %s", ErrInstanceNotFound, privateDNS)
+	case 1:
+		return instances[0], nil
+	default:
+		instanceIDs := make([]string, 0, len(instances))
+		for _, instance := range instances {
+			instanceIDs = append(instanceIDs, aws.StringValue(instance.InstanceId))
+		}
+		return nil, fmt.Errorf("found %d instances for private-dns-name %s: %s", len(instances), privateDNS, strings.Join(instanceIDs, ", "))
+	}
+}
+
+// ErrInstanceNotFound is returned when the requested instance does not exist.
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// errConsoleOutputNotAvailable is returned by getConsoleOutput when EC2 has no console output
+// for the instance yet, which is normal shortly after launch.
+var errConsoleOutputNotAvailable = errors.New("console output not yet available")
+
+// getConsoleOutput returns the decoded serial console output of the instance.
+// This is useful for debugging instances which never join the cluster, e.g. because of ignition failures.
+func getConsoleOutput(client awsclient.Client, instanceID string) (string, error) {
+	if instanceID == "" {
+		return "", fmt.Errorf("instance-id not specified")
+	}
+
+	result, err := client.GetConsoleOutput(&ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting console output for instance %s: %v", instanceID, err)
+	}
+
+	if result == nil || aws.StringValue(result.Output) == "" {
+		return "", errConsoleOutputNotAvailable
+	}
+
+	output, err := base64.StdEncoding.DecodeString(aws.StringValue(result.Output))
+	if err != nil {
+		return "", fmt.Errorf("error decoding console output for instance %s: %v", instanceID, err)
+	}
+
+	return string(output), nil
+}
+
+// restoreClusterTag re-applies the cluster tag to an instance found by the ID stored in the provider status
+// when the tag is missing, e.g. because an external process or a tag policy stripped it. Without the tag
+// the instance can no longer be found by the tag filters and would be orphaned once the stored ID is lost.
+// The instance tags are updated in place on success.
+func restoreClusterTag(machine *machinev1.Machine, instance *ec2.Instance, client awsclient.Client) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	clusterID, ok := getClusterID(machine)
+	if !ok {
+		return fmt.Errorf("unable to get cluster ID for machine: %q", machine.Name)
+	}
+
+	key := clusterFilterKey(clusterID)
+	if hasTagKey(instance.Tags, key) {
+		return nil
+	}
+
+	klog.Warningf("%s: Instance %s is missing cluster tag %q, restoring it", machine.Name, *instance.InstanceId, key)
+	tag := &ec2.Tag{Key: aws.String(key), Value: aws.String(clusterFilterValue)}
+	err := createTagsWithRetry(client, &ec2.CreateTagsInput{
+		Resources: []*string{instance.InstanceId},
+		Tags:      []*ec2.Tag{tag},
+	})
+	if err != nil {
+		klog.Errorf("Error restoring cluster tag on instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error restoring cluster tag on instance %s: %v", *instance.InstanceId, err)
+	}
+	instance.Tags = append(instance.Tags, tag)
+	return nil
 }
 
 // correctExistingTags validates Name and clusterID tags are correct on the instance
-// and sets them if they are not.
+// and sets them if they are not, along with the given tags.
+// Only the tags missing from the instance, or whose value differs, are applied.
 func correctExistingTags(machine *machinev1.Machine, instance *ec2.Instance, client awsclient.Client, tags map[string]string) error {
 	// https://docs.aws.amazon.com/sdk-for-go/api/service/ec2/#EC2.CreateTags
 	if instance == nil || instance.InstanceId == nil {
@@ -145,58 +390,247 @@ func correctExistingTags(machine *machinev1.Machine, instance *ec2.Instance, cli
 	if !ok {
 		return fmt.Errorf("unable to get cluster ID for machine: %q", machine.Name)
 	}
-	nameTagOk := false
-	clusterTagOk := false
-	for _, tag := range instance.Tags {
-		if tag.Key != nil && tag.Value != nil {
-			if *tag.Key == "Name" && *tag.Value == machine.Name {
-				nameTagOk = true
-			}
-			if *tag.Key == "kubernetes.io/cluster/"+clusterID && *tag.Value == "owned" {
-				clusterTagOk = true
-			}
-			if tagValue, present := tags[*tag.Key]; present && *tag.Value == tagValue {
-				delete(tags, *tag.Key)
-			}
+
+	desired := make(map[string]string, len(tags)+2)
+	for key, value := range tags {
+		desired[key] = value
+	}
+	desired[clusterFilterKey(clusterID)] = clusterFilterValue
+	desired["Name"] = machine.Name
+
+	tagsToAdd, _ := computeTagChanges(instance.Tags, desired, nil)
+	if len(tagsToAdd) != 0 {
+		klog.Infof("updating Tags for machine: %v; instanceID: %v, tags: %+v",
+			machine.Name, *instance.InstanceId, tagsToAdd)
+	}
+	err := applyTagChanges(client, instance, tagsToAdd, nil)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ec2TagPolicyViolationErrorCode {
+		return skipTagPolicyViolation(client, instance, tagsToAdd, aerr, clusterFilterKey(clusterID), "Name")
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ec2UnauthorizedOperationErrorCode {
+		return &taggingUnauthorizedError{instanceID: *instance.InstanceId, err: aerr}
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ec2TagLimitExceededErrorCode {
+		keys := []string{}
+		for _, tag := range tagsToAdd {
+			keys = append(keys, aws.StringValue(tag.Key))
 		}
+		return machinecontroller.InvalidMachineConfiguration("instance %s would exceed the EC2 limit of %d tags per resource, unable to apply tags: %s",
+			*instance.InstanceId, maxInstanceTags, strings.Join(keys, ", "))
 	}
+	return err
+}
 
-	tagsToAdd := []*ec2.Tag{}
-	for key, value := range tags {
-		tagsToAdd = append(tagsToAdd, &ec2.Tag{
-			Key:   aws.String(key),
-			Value: aws.String(value),
-		})
+// skipTagPolicyViolation applies the tags again without the one that violates the tag policy of the organization.
+// The returned tagPolicyViolationError records whether the other tags could be applied. Tags whose key is in
+// requiredKeys, and violations whose tag cannot be identified, are not skipped.
+func skipTagPolicyViolation(client awsclient.Client, instance *ec2.Instance, tagsToAdd []*ec2.Tag, violation awserr.Error, requiredKeys ...string) error {
+	instanceID := aws.StringValue(instance.InstanceId)
+	violationErr := &tagPolicyViolationError{instanceID: instanceID, tagKey: tagPolicyViolationKey(violation.Message()), err: violation}
+	if violationErr.tagKey == "" || stringSliceContains(requiredKeys, violationErr.tagKey) {
+		return violationErr
 	}
 
-	if !nameTagOk || !clusterTagOk {
-		tagsToAdd = append(tagsToAdd, &ec2.Tag{
-			Key:   aws.String("kubernetes.io/cluster/" + clusterID),
-			Value: aws.String("owned"),
-		})
-		tagsToAdd = append(tagsToAdd, &ec2.Tag{
-			Key:   aws.String("Name"),
-			Value: aws.String(machine.Name),
-		})
+	remaining := make([]*ec2.Tag, 0, len(tagsToAdd))
+	for _, tag := range tagsToAdd {
+		if aws.StringValue(tag.Key) != violationErr.tagKey {
+			remaining = append(remaining, tag)
+		}
+	}
+	klog.Warningf("Skipping tag %q of instance %s violating the tag policy: %v", violationErr.tagKey, instanceID, violation)
+	if err := applyTagChanges(client, instance, remaining, nil); err != nil {
+		return err
+	}
+	violationErr.skipped = true
+	return violationErr
+}
+
+// computeTagChanges returns the minimal set of changes turning the current tags of a resource into the desired ones:
+// the desired tags that are missing or whose value differs, and the keys of the stale tags still present.
+// Values are compared with surrounding whitespace trimmed, as EC2 trims stored values. Stale keys that are also
+// desired are never deleted. Both results are sorted by key.
+func computeTagChanges(current []*ec2.Tag, desired map[string]string, stale []string) ([]*ec2.Tag, []string) {
+	currentTags := tagsToMap(current)
+
+	addKeys := []string{}
+	for key, value := range desired {
+		if currentValue, present := currentTags[key]; !present || strings.TrimSpace(currentValue) != strings.TrimSpace(value) {
+			addKeys = append(addKeys, key)
+		}
+	}
+	sort.Strings(addKeys)
+	tagsToAdd := make([]*ec2.Tag, 0, len(addKeys))
+	for _, key := range addKeys {
+		tagsToAdd = append(tagsToAdd, &ec2.Tag{Key: aws.String(key), Value: aws.String(desired[key])})
 	}
 
+	keysToDelete := []string{}
+	for _, key := range stale {
+		if _, isDesired := desired[key]; isDesired {
+			continue
+		}
+		if _, present := currentTags[key]; present && !stringSliceContains(keysToDelete, key) {
+			keysToDelete = append(keysToDelete, key)
+		}
+	}
+	sort.Strings(keysToDelete)
+
+	return tagsToAdd, keysToDelete
+}
+
+// applyTagChanges applies the changes computed by computeTagChanges to the instance with at most one CreateTags
+// and one DeleteTags call, skipping the calls with nothing to change. The deletions go through
+// deleteStaleInstanceTags, so keys written since the instance was described are not deleted.
+// Errors from CreateTags are returned unwrapped so that callers can inspect the AWS error code.
+func applyTagChanges(client awsclient.Client, instance *ec2.Instance, tagsToAdd []*ec2.Tag, keysToDelete []string) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
 	if len(tagsToAdd) != 0 {
 		// Create tags only adds/replaces what is present, does not affect other tags.
-		input := &ec2.CreateTagsInput{
-			Resources: []*string{
-				aws.String(*instance.InstanceId),
-			},
-			Tags: tagsToAdd,
+		err := createTagsWithRetry(client, &ec2.CreateTagsInput{
+			Resources: []*string{instance.InstanceId},
+			Tags:      tagsToAdd,
+		})
+		if err != nil {
+			return err
 		}
-		klog.Infof("updating Tags for machine: %v; instanceID: %v, tags: %+v",
-			machine.Name, *instance.InstanceId, tagsToAdd)
-		_, err := client.CreateTags(input)
-		return err
+	}
+	return deleteStaleInstanceTags(client, instance, keysToDelete)
+}
+
+// tagRetryBackoff bounds the attempts createTagsWithRetry makes when CreateTags is throttled.
+var tagRetryBackoff = wait.Backoff{Duration: time.Second, Factor: 2, Steps: 3}
+
+// createTagsWithRetry calls CreateTags and retries it with backoff while the calls are throttled.
+// Other errors are returned immediately. When all attempts are throttled the last error is returned.
+func createTagsWithRetry(client awsclient.Client, input *ec2.CreateTagsInput) error {
+	var lastErr error
+	attempts := 0
+	err := wait.ExponentialBackoff(tagRetryBackoff, func() (bool, error) {
+		attempts++
+		_, lastErr = client.CreateTags(input)
+		if lastErr == nil {
+			return true, nil
+		}
+		if isThrottlingError(lastErr) {
+			klog.Warningf("Tagging %v was throttled on attempt %d: %v", aws.StringValueSlice(input.Resources), attempts, lastErr)
+			return false, nil
+		}
+		return false, lastErr
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		klog.Errorf("Error tagging %v: still throttled after %d attempts: %v", aws.StringValueSlice(input.Resources), attempts, lastErr)
+		return lastErr
+	}
+	return err
+}
+
+// correctTagsForInstances applies correctExistingTags to each of the instances concurrently,
+// using at most maxConcurrentTagCorrections workers, and aggregates the resulting errors.
+func correctTagsForInstances(machine *machinev1.Machine, instances []*ec2.Instance, client awsclient.Client, tags map[string]string) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	workers := make(chan struct{}, maxConcurrentTagCorrections)
+
+	for _, instance := range instances {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(instance *ec2.Instance) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			if err := correctExistingTags(machine, instance, client, tags); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", aws.StringValue(instance.InstanceId), err))
+				mu.Unlock()
+			}
+		}(instance)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs)
+	}
+	return nil
+}
+
+// deleteInstanceTags removes the tags with the given keys from the instance.
+// Only the keys are sent to EC2: a tag with a value in the request is only deleted
+// when the stored value matches, while a key alone deletes the tag whatever its value is.
+func deleteInstanceTags(client awsclient.Client, instanceID string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sortedKeys := append([]string{}, keys...)
+	sort.Strings(sortedKeys)
+	tags := make([]*ec2.Tag, 0, len(sortedKeys))
+	for _, key := range sortedKeys {
+		tags = append(tags, &ec2.Tag{Key: aws.String(key)})
 	}
 
+	klog.Infof("Deleting tags %v from instance %s", sortedKeys, instanceID)
+	_, err := client.DeleteTags(&ec2.DeleteTagsInput{
+		Resources: aws.StringSlice([]string{instanceID}),
+		Tags:      tags,
+	})
+	if err != nil {
+		klog.Errorf("Error deleting tags from instance %s: %v", instanceID, err)
+		return fmt.Errorf("error deleting tags from instance %s: %v", instanceID, err)
+	}
 	return nil
 }
 
+// deleteStaleInstanceTags removes the tags with the given keys from the instance, as observed in its tags.
+// DeleteTags is not conditional, so another controller writing one of the keys after the instance was described
+// would have its tag deleted. To narrow that window the tags are described again right before deleting them,
+// and keys whose value changed since they were observed, or that reappeared after being removed, are skipped.
+// No API call is made when there are no keys to delete.
+func deleteStaleInstanceTags(client awsclient.Client, instance *ec2.Instance, keys []string) error {
+	if instance == nil || instance.InstanceId == nil {
+		return fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	observed := tagsToMap(instance.Tags)
+	current, err := getInstanceByID(*instance.InstanceId, client, nil)
+	if err != nil {
+		klog.Errorf("Error describing tags of instance %s: %v", *instance.InstanceId, err)
+		return fmt.Errorf("error describing tags of instance %s: %v", *instance.InstanceId, err)
+	}
+	currentTags := tagsToMap(current.Tags)
+
+	toDelete := []string{}
+	for _, key := range keys {
+		currentValue, present := currentTags[key]
+		if !present {
+			continue
+		}
+		if observedValue, ok := observed[key]; !ok || observedValue != currentValue {
+			klog.Infof("Skipping deletion of tag %q from instance %s: it was written since the instance was described", key, *instance.InstanceId)
+			continue
+		}
+		toDelete = append(toDelete, key)
+	}
+
+	return deleteInstanceTags(client, *instance.InstanceId, toDelete)
+}
+
+func tagsToMap(tags []*ec2.Tag) map[string]string {
+	tagMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagMap[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tagMap
+}
+
 // getInstances returns all instances that have a tag matching our machine name,
 // and cluster ID.
 func getInstances(machine *machinev1.Machine, client awsclient.Client, instanceStateFilter []*string) ([]*ec2.Instance, error) {
@@ -238,14 +672,50 @@ func getInstances(machine *machinev1.Machine, client awsclient.Client, instanceS
 	return instances, nil
 }
 
+// detectDuplicateNameTags returns the IDs of instances sharing a Name tag, keyed by the Name,
+// for every Name carried by more than one instance. Instances without a Name tag are ignored.
+// Duplicates usually indicate that an instance was launched twice for the same machine.
+func detectDuplicateNameTags(instances []*ec2.Instance) map[string][]string {
+	instancesByName := map[string][]string{}
+	for _, instance := range instances {
+		if instance == nil {
+			continue
+		}
+		for _, tag := range instance.Tags {
+			if aws.StringValue(tag.Key) == "Name" && aws.StringValue(tag.Value) != "" {
+				name := aws.StringValue(tag.Value)
+				instancesByName[name] = append(instancesByName[name], aws.StringValue(instance.InstanceId))
+				break
+			}
+		}
+	}
+
+	duplicates := map[string][]string{}
+	for name, instanceIDs := range instancesByName {
+		if len(instanceIDs) > 1 {
+			duplicates[name] = instanceIDs
+		}
+	}
+	return duplicates
+}
+
 // terminateInstances terminates all provided instances with a single EC2 request.
+// Instances which are already terminated, or which EC2 no longer knows about,
+// are considered successfully terminated so that retries do not block cleanup.
 func terminateInstances(client awsclient.Client, instances []*ec2.Instance) ([]*ec2.InstanceStateChange, error) {
 	instanceIDs := []*string{}
 	// Cleanup all older instances:
 	for _, instance := range instances {
 		klog.Infof("Cleaning up extraneous instance for machine: %v, state: %v, launchTime: %v", *instance.InstanceId, *instance.State.Name, *instance.LaunchTime)
+		if aws.StringValue(instance.State.Name) == ec2.InstanceStateNameTerminated {
+			klog.Infof("Instance %v is already terminated, skipping", *instance.InstanceId)
+			continue
+		}
 		instanceIDs = append(instanceIDs, instance.InstanceId)
 	}
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
 	for _, instanceID := range instanceIDs {
 		klog.Infof("Terminating %v instance", *instanceID)
 	}
@@ -255,6 +725,12 @@ func terminateInstances(client awsclient.Client, instances []*ec2.Instance) ([]*
 	}
 	output, err := client.TerminateInstances(terminateInstancesRequest)
 	if err != nil {
+		if isInstanceNotFoundError(err) {
+			// The batched request fails as a whole if any of the instances is gone,
+			// so fall back to terminating the instances one at a time.
+			klog.Infof("Some instances were not found, terminating instances individually: %v", err)
+			return terminateInstancesIndividually(client, instanceIDs)
+		}
 		klog.Errorf("Error terminating instances: %v", err)
 		return nil, fmt.Errorf("error terminating instances: %v", err)
 	}
@@ -266,6 +742,168 @@ func terminateInstances(client awsclient.Client, instances []*ec2.Instance) ([]*
 	return output.TerminatingInstances, nil
 }
 
+// terminateInstancesIndividually sends a terminate request per instance ID,
+// ignoring instances which no longer exist.
+func terminateInstancesIndividually(client awsclient.Client, instanceIDs []*string) ([]*ec2.InstanceStateChange, error) {
+	terminatingInstances := []*ec2.InstanceStateChange{}
+	errs := []error{}
+	for _, instanceID := range instanceIDs {
+		output, err := client.TerminateInstances(&ec2.TerminateInstancesInput{
+			InstanceIds: []*string{instanceID},
+		})
+		if err != nil {
+			if isInstanceNotFoundError(err) {
+				klog.Infof("Instance %v not found, considering it terminated", *instanceID)
+				continue
+			}
+			klog.Errorf("Error terminating instance %v: %v", *instanceID, err)
+			errs = append(errs, fmt.Errorf("%s: %v", *instanceID, err))
+			continue
+		}
+		if output != nil {
+			terminatingInstances = append(terminatingInstances, output.TerminatingInstances...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return terminatingInstances, fmt.Errorf("error terminating instances: %v", errorutil.NewAggregate(errs))
+	}
+	return terminatingInstances, nil
+}
+
+// instanceTerminationPollInterval is how often waitForInstanceTerminated checks the instance state.
+var instanceTerminationPollInterval = 5 * time.Second
+
+// waitForInstanceTerminated polls the instance until it reaches the terminated state or the timeout elapses.
+// An instance that is no longer known to EC2 is considered terminated.
+// On timeout the returned error contains the last observed state of the instance.
+func waitForInstanceTerminated(client awsclient.Client, id string, timeout time.Duration) error {
+	lastState := "unknown"
+	err := wait.PollImmediate(instanceTerminationPollInterval, timeout, func() (bool, error) {
+		instance, err := getInstanceByID(id, client, nil)
+		if err != nil {
+			if errors.Is(err, ErrInstanceNotFound) {
+				return true, nil
+			}
+			klog.Errorf("Error getting instance %s while waiting for termination: %v", id, err)
+			return false, nil
+		}
+		lastState = aws.StringValue(instance.State.Name)
+		return lastState == ec2.InstanceStateNameTerminated, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for instance %s to terminate, last observed state: %s", id, lastState)
+	}
+	return nil
+}
+
+// isInstanceNotFoundError returns true if the error is the EC2 error returned for unknown instance IDs.
+func isInstanceNotFoundError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == ec2InstanceNotFoundErrorCode
+	}
+	return false
+}
+
+// terminalErrorCodes are the EC2 and ELB error codes caused by the machine configuration.
+// Retrying a request that failed with one of them fails the same way until the configuration changes.
+var terminalErrorCodes = map[string]struct{}{
+	"InvalidParameterValue":       {},
+	"InvalidParameterCombination": {},
+	"InvalidAMIID.NotFound":       {},
+	"InvalidAMIID.Malformed":      {},
+	"InvalidSubnetID.NotFound":    {},
+	"InvalidGroup.NotFound":       {},
+	"InvalidKeyPair.NotFound":     {},
+	"InvalidBlockDeviceMapping":   {},
+	"InvalidInstanceType":         {},
+	"Unsupported":                 {},
+	"UnauthorizedOperation":       {},
+	// Classic and v2 load balancers share the LoadBalancerNotFound code
+	elb.ErrCodeAccessPointNotFoundException:   {},
+	elbv2.ErrCodeTargetGroupNotFoundException: {},
+}
+
+// isTerminalError returns true if the error is an AWS error that retrying will not resolve,
+// so that the actuator can report it as a failure rather than requeue.
+// Throttling, quota and server side errors are not terminal.
+func isTerminalError(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	_, ok := terminalErrorCodes[aerr.Code()]
+	return ok
+}
+
+// isThrottlingError returns true if the error is an AWS error reporting that the request was throttled.
+func isThrottlingError(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && request.IsErrorThrottle(aerr)
+}
+
+// describeInstanceLifecycleState returns the Auto Scaling lifecycle state of the instance,
+// e.g. InService or Warmed:Stopped for instances parked in a warm pool.
+// An empty state is returned when the instance is not managed by an Auto Scaling group.
+func describeInstanceLifecycleState(client awsclient.Client, instanceID string) (string, error) {
+	result, err := client.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing auto scaling lifecycle state of instance %s: %v", instanceID, err)
+		return "", fmt.Errorf("error describing auto scaling lifecycle state of instance %s: %v", instanceID, err)
+	}
+
+	for _, instance := range result.AutoScalingInstances {
+		if aws.StringValue(instance.InstanceId) == instanceID {
+			return aws.StringValue(instance.LifecycleState), nil
+		}
+	}
+
+	return "", nil
+}
+
+// isWarmPoolLifecycleState returns true if the Auto Scaling lifecycle state belongs to a warm pool instance.
+func isWarmPoolLifecycleState(lifecycleState string) bool {
+	return strings.HasPrefix(lifecycleState, "Warmed:")
+}
+
+// protectInstanceFromScaleIn protects the instance from scale in by the Auto Scaling group it is attached to, if any,
+// so that the group does not terminate an instance backing a machine.
+// Instances outside of Auto Scaling groups, or already protected, are left untouched.
+func protectInstanceFromScaleIn(client awsclient.Client, instanceID string) error {
+	result, err := client.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing auto scaling group of instance %s: %v", instanceID, err)
+		return fmt.Errorf("error describing auto scaling group of instance %s: %v", instanceID, err)
+	}
+
+	for _, instance := range result.AutoScalingInstances {
+		if aws.StringValue(instance.InstanceId) != instanceID || aws.StringValue(instance.AutoScalingGroupName) == "" {
+			continue
+		}
+		if aws.BoolValue(instance.ProtectedFromScaleIn) {
+			return nil
+		}
+
+		groupName := aws.StringValue(instance.AutoScalingGroupName)
+		klog.Infof("Protecting instance %s from scale in by auto scaling group %s", instanceID, groupName)
+		_, err := client.SetInstanceProtection(&autoscaling.SetInstanceProtectionInput{
+			AutoScalingGroupName: aws.String(groupName),
+			InstanceIds:          aws.StringSlice([]string{instanceID}),
+			ProtectedFromScaleIn: aws.Bool(true),
+		})
+		if err != nil {
+			klog.Errorf("Error protecting instance %s from scale in by auto scaling group %s: %v", instanceID, groupName, err)
+			return fmt.Errorf("error protecting instance %s from scale in by auto scaling group %s: %v", instanceID, groupName, err)
+		}
+	}
+
+	return nil
+}
+
 // setAWSMachineProviderCondition sets the condition for the machine and
 // returns the new slice of conditions.
 // If the machine does not already have a condition with the specified type,
@@ -313,6 +951,9 @@ func shouldUpdateCondition(newCondition, existingCondition *machinev1.AWSMachine
 	return newCondition.Reason != existingCondition.Reason || newCondition.Message != existingCondition.Message
 }
 
+// defaultPrivateDNSNameRegexp matches the private DNS names assigned by EC2 by default, e.g. ip-10-0-0-5.ec2.internal.
+var defaultPrivateDNSNameRegexp = regexp.MustCompile(`^ip-[0-9]{1,3}-[0-9]{1,3}-[0-9]{1,3}-[0-9]{1,3}(\.|$)`)
+
 // extractNodeAddresses maps the instance information from EC2 to an array of NodeAddresses
 func extractNodeAddresses(instance *ec2.Instance, domainNames []string) ([]corev1.NodeAddress, error) {
 	// Not clear if the order matters here, but we might as well indicate a sensible preference order
@@ -369,6 +1010,11 @@ func extractNodeAddresses(instance *ec2.Instance, domainNames []string) ([]corev
 	if privateDNSName != "" {
 		addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: privateDNSName})
 		addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeHostName, Address: privateDNSName})
+		// Custom host names are only synthesized from the default EC2 private DNS names. Names from a custom
+		// DHCP option set are already the host names the node is known by.
+		if !defaultPrivateDNSNameRegexp.MatchString(privateDNSName) {
+			domainNames = nil
+		}
 		for _, dn := range domainNames {
 			customHostName := strings.Join([]string{strings.Split(privateDNSName, ".")[0], dn}, ".")
 			if customHostName != privateDNSName {
@@ -385,12 +1031,85 @@ func extractNodeAddresses(instance *ec2.Instance, domainNames []string) ([]corev
 	return addresses, nil
 }
 
-func conditionSuccess() machinev1.AWSMachineProviderCondition {
+// resolvePrivateHostedZoneDomains returns the domain names of the Route53 private hosted zones associated with the VPC,
+// sorted and without trailing dot, so that they can be passed to extractNodeAddresses as custom domain names.
+// The region is the region of the VPC, which Route53 requires alongside the VPC ID.
+func resolvePrivateHostedZoneDomains(client awsclient.Client, vpcID, region string) ([]string, error) {
+	domains := map[string]bool{}
+	input := &route53.ListHostedZonesByVPCInput{
+		VPCId:     aws.String(vpcID),
+		VPCRegion: aws.String(region),
+	}
+	for {
+		result, err := client.ListHostedZonesByVPC(input)
+		if err != nil {
+			klog.Errorf("Error listing hosted zones of VPC %s: %v", vpcID, err)
+			return nil, fmt.Errorf("error listing hosted zones of VPC %s: %v", vpcID, err)
+		}
+		for _, zone := range result.HostedZoneSummaries {
+			if domain := strings.TrimSuffix(aws.StringValue(zone.Name), "."); domain != "" {
+				domains[domain] = true
+			}
+		}
+		if aws.StringValue(result.NextToken) == "" {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+	domainNames := make([]string, 0, len(domains))
+	for domain := range domains {
+		domainNames = append(domainNames, domain)
+	}
+	sort.Strings(domainNames)
+	return domainNames, nil
+}
+
+// InstanceMeta holds the placement and lifecycle details of an instance that nodes can be labeled with.
+type InstanceMeta struct {
+	// Tenancy is the placement tenancy of the instance: default, dedicated or host
+	Tenancy string
+	// AvailabilityZone is the availability zone the instance runs in
+	AvailabilityZone string
+	// InstanceType is the EC2 instance type
+	InstanceType string
+	// Lifecycle is spot or scheduled for such instances and on-demand otherwise
+	Lifecycle string
+}
+
+// extractInstanceMetadata maps the instance information from EC2 to an InstanceMeta.
+// Details missing from the instance are left empty.
+func extractInstanceMetadata(instance *ec2.Instance) InstanceMeta {
+	if instance == nil {
+		return InstanceMeta{}
+	}
+
+	meta := InstanceMeta{
+		InstanceType: aws.StringValue(instance.InstanceType),
+		Lifecycle:    aws.StringValue(instance.InstanceLifecycle),
+	}
+	if meta.Lifecycle == "" {
+		meta.Lifecycle = ec2.InstanceLifecycleOnDemand
+	}
+	if instance.Placement != nil {
+		meta.Tenancy = aws.StringValue(instance.Placement.Tenancy)
+		meta.AvailabilityZone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+
+	return meta
+}
+
+// conditionSuccess returns the successful MachineCreation condition.
+// When the launch time of the instance is known it is included in the message.
+func conditionSuccess(instance *ec2.Instance) machinev1.AWSMachineProviderCondition {
+	message := "Machine successfully created"
+	if instance != nil && instance.LaunchTime != nil {
+		message = fmt.Sprintf("%s, instance launched at %s", message, instance.LaunchTime.UTC().Format(time.RFC3339))
+	}
 	return machinev1.AWSMachineProviderCondition{
 		Type:    machinev1.MachineCreation,
 		Status:  corev1.ConditionTrue,
 		Reason:  machinev1.MachineCreationSucceededConditionReason,
-		Message: "Machine successfully created",
+		Message: message,
 	}
 }
 
@@ -402,6 +1121,43 @@ func conditionFailed() machinev1.AWSMachineProviderCondition {
 	}
 }
 
+// launchFailedCondition returns the failed condition for an error returned by launchInstance.
+// Errors caused by an exhausted account quota get the QuotaExceeded reason, so that they can be told
+// apart from configuration errors and routed to a quota increase.
+func launchFailedCondition(err error) machinev1.AWSMachineProviderCondition {
+	condition := conditionFailed()
+	condition.Message = err.Error()
+
+	var quotaErr *quotaExceededError
+	if errors.As(err, &quotaErr) {
+		condition.Reason = QuotaExceededConditionReason
+	}
+	return condition
+}
+
+// quotaExceededError is returned by launchInstance when EC2 rejects the launch because an account quota is exhausted.
+type quotaExceededError struct {
+	quota string
+	err   error
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("%s: %v", e.quota, e.err)
+}
+
+func (e *quotaExceededError) Unwrap() error {
+	return e.err
+}
+
+// isQuotaExceededErrorCode returns true if the EC2 error code reports an exhausted account quota.
+func isQuotaExceededErrorCode(code string) bool {
+	switch code {
+	case "InstanceLimitExceeded", "VcpuLimitExceeded":
+		return true
+	}
+	return false
+}
+
 // validateMachine check the label that a machine must have to identify the cluster to which it belongs is present.
 func validateMachine(machine machinev1.Machine) error {
 	if machine.Labels[machinev1.MachineClusterIDLabel] == "" {
@@ -1,11 +1,22 @@
 package machine
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/golang/mock/gomock"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -61,7 +72,7 @@ func TestExtractNodeAddresses(t *testing.T) {
 		{
 			testcase: "custom-domain",
 			instance: &ec2.Instance{
-				PrivateDnsName: aws.String("ec2.example.net"),
+				PrivateDnsName: aws.String("ip-10-0-0-5.ec2.internal"),
 				NetworkInterfaces: []*ec2.InstanceNetworkInterface{
 					{
 						Status: aws.String(ec2.NetworkInterfaceStatusInUse),
@@ -76,17 +87,17 @@ func TestExtractNodeAddresses(t *testing.T) {
 			},
 			expectedAddresses: []corev1.NodeAddress{
 				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
-				{Type: corev1.NodeInternalDNS, Address: "ec2.example.net"},
-				{Type: corev1.NodeHostName, Address: "ec2.example.net"},
-				{Type: corev1.NodeInternalDNS, Address: "ec2.openshift.com"},
-				{Type: corev1.NodeInternalDNS, Address: "ec2.openshift.io"},
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-5.ec2.internal"},
+				{Type: corev1.NodeHostName, Address: "ip-10-0-0-5.ec2.internal"},
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-5.openshift.com"},
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-5.openshift.io"},
 			},
 			domainNames: []string{"openshift.com", "openshift.io"},
 		},
 		{
 			testcase: "custom-domain no duplicates",
 			instance: &ec2.Instance{
-				PrivateDnsName: aws.String("ec2.example.net"),
+				PrivateDnsName: aws.String("ip-10-0-0-5.ec2.internal"),
 				NetworkInterfaces: []*ec2.InstanceNetworkInterface{
 					{
 						Status: aws.String(ec2.NetworkInterfaceStatusInUse),
@@ -101,10 +112,33 @@ func TestExtractNodeAddresses(t *testing.T) {
 			},
 			expectedAddresses: []corev1.NodeAddress{
 				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
-				{Type: corev1.NodeInternalDNS, Address: "ec2.example.net"},
-				{Type: corev1.NodeHostName, Address: "ec2.example.net"},
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-5.ec2.internal"},
+				{Type: corev1.NodeHostName, Address: "ip-10-0-0-5.ec2.internal"},
 			},
-			domainNames: []string{"example.net", "example.net"},
+			domainNames: []string{"ec2.internal", "ec2.internal"},
+		},
+		{
+			testcase: "custom-domain custom private DNS name",
+			instance: &ec2.Instance{
+				PrivateDnsName: aws.String("worker-1.corp.example.net"),
+				NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+					{
+						Status: aws.String(ec2.NetworkInterfaceStatusInUse),
+						PrivateIpAddresses: []*ec2.InstancePrivateIpAddress{
+							{
+								Primary:          aws.Bool(true),
+								PrivateIpAddress: aws.String("10.0.0.5"),
+							},
+						},
+					},
+				},
+			},
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+				{Type: corev1.NodeInternalDNS, Address: "worker-1.corp.example.net"},
+				{Type: corev1.NodeHostName, Address: "worker-1.corp.example.net"},
+			},
+			domainNames: []string{"openshift.com", "openshift.io"},
 		},
 		{
 			testcase: "multiple-private",
@@ -183,3 +217,1238 @@ func TestExtractNodeAddresses(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvePrivateHostedZoneDomains(t *testing.T) {
+	zone := func(name string) *route53.HostedZoneSummary {
+		return &route53.HostedZoneSummary{HostedZoneId: aws.String("Z" + name), Name: aws.String(name)}
+	}
+	input := func(nextToken *string) *route53.ListHostedZonesByVPCInput {
+		return &route53.ListHostedZonesByVPCInput{VPCId: aws.String("vpc-1"), VPCRegion: aws.String("us-east-1"), NextToken: nextToken}
+	}
+
+	testCases := []struct {
+		name          string
+		expectations  func(*mockaws.MockClientMockRecorder)
+		expected      []string
+		expectedError string
+	}{
+		{
+			name: "Private zones of the VPC",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ListHostedZonesByVPC(input(nil)).Return(&route53.ListHostedZonesByVPCOutput{
+					HostedZoneSummaries: []*route53.HostedZoneSummary{zone("example.com."), zone("cluster.example.com.")},
+				}, nil)
+			},
+			expected: []string{"cluster.example.com", "example.com"},
+		},
+		{
+			name: "Paginated zones",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ListHostedZonesByVPC(input(nil)).Return(&route53.ListHostedZonesByVPCOutput{
+					HostedZoneSummaries: []*route53.HostedZoneSummary{zone("example.com.")},
+					NextToken:           aws.String("token"),
+				}, nil)
+				m.ListHostedZonesByVPC(input(aws.String("token"))).Return(&route53.ListHostedZonesByVPCOutput{
+					HostedZoneSummaries: []*route53.HostedZoneSummary{zone("example.org."), zone("example.com.")},
+				}, nil)
+			},
+			expected: []string{"example.com", "example.org"},
+		},
+		{
+			name: "No zones",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ListHostedZonesByVPC(input(nil)).Return(&route53.ListHostedZonesByVPCOutput{}, nil)
+			},
+			expected: []string{},
+		},
+		{
+			name: "Error listing zones",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.ListHostedZonesByVPC(input(nil)).Return(nil, errors.New("access denied"))
+			},
+			expectedError: "error listing hosted zones of VPC vpc-1: access denied",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			domains, err := resolvePrivateHostedZoneDomains(mockAWSClient, "vpc-1", "us-east-1")
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(domains, tc.expected) {
+				t.Errorf("Expected domains %v, got %v", tc.expected, domains)
+			}
+		})
+	}
+}
+
+func TestDetectStuckPending(t *testing.T) {
+	instance := func(id, state string, launchTime *time.Time) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId: aws.String(id),
+			State:      &ec2.InstanceState{Name: aws.String(state)},
+			LaunchTime: launchTime,
+		}
+	}
+	old := aws.Time(time.Now().Add(-30 * time.Minute))
+	fresh := aws.Time(time.Now().Add(-time.Minute))
+
+	instances := []*ec2.Instance{
+		instance("i-stuck", ec2.InstanceStateNamePending, old),
+		instance("i-fresh", ec2.InstanceStateNamePending, fresh),
+		instance("i-no-launch-time", ec2.InstanceStateNamePending, nil),
+		instance("i-running", ec2.InstanceStateNameRunning, old),
+		nil,
+	}
+
+	ids := []string{}
+	for _, instance := range detectStuckPending(instances, 10*time.Minute) {
+		ids = append(ids, aws.StringValue(instance.InstanceId))
+	}
+	if expected := []string{"i-stuck"}; !equality.Semantic.DeepEqual(ids, expected) {
+		t.Errorf("Expected stuck instances %v, got %v", expected, ids)
+	}
+}
+
+func TestExtractInstanceMetadata(t *testing.T) {
+	testCases := []struct {
+		name     string
+		instance *ec2.Instance
+		expected InstanceMeta
+	}{
+		{
+			name: "All fields populated",
+			instance: &ec2.Instance{
+				InstanceType:      aws.String("m5.large"),
+				InstanceLifecycle: aws.String(ec2.InstanceLifecycleTypeSpot),
+				Placement: &ec2.Placement{
+					Tenancy:          aws.String(ec2.TenancyDedicated),
+					AvailabilityZone: aws.String("us-east-1a"),
+				},
+			},
+			expected: InstanceMeta{
+				Tenancy:          ec2.TenancyDedicated,
+				AvailabilityZone: "us-east-1a",
+				InstanceType:     "m5.large",
+				Lifecycle:        ec2.InstanceLifecycleTypeSpot,
+			},
+		},
+		{
+			name: "On-demand instance",
+			instance: &ec2.Instance{
+				InstanceType: aws.String("m5.large"),
+				Placement: &ec2.Placement{
+					Tenancy:          aws.String(ec2.TenancyDefault),
+					AvailabilityZone: aws.String("us-east-1b"),
+				},
+			},
+			expected: InstanceMeta{
+				Tenancy:          ec2.TenancyDefault,
+				AvailabilityZone: "us-east-1b",
+				InstanceType:     "m5.large",
+				Lifecycle:        ec2.InstanceLifecycleOnDemand,
+			},
+		},
+		{
+			name: "Nil instance",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if meta := extractInstanceMetadata(tc.instance); meta != tc.expected {
+				t.Errorf("Expected metadata %+v, got %+v", tc.expected, meta)
+			}
+		})
+	}
+}
+
+func TestTerminateInstances(t *testing.T) {
+	notFoundErr := awserr.New(ec2InstanceNotFoundErrorCode, "The instance ID 'i-2' does not exist", nil)
+	terminatingState := func(id string) *ec2.InstanceStateChange {
+		return &ec2.InstanceStateChange{
+			InstanceId:   aws.String(id),
+			CurrentState: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameShuttingDown)},
+		}
+	}
+	instance := func(id, state string) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId: aws.String(id),
+			State:      &ec2.InstanceState{Name: aws.String(state)},
+			LaunchTime: aws.Time(time.Now()),
+		}
+	}
+
+	testCases := []struct {
+		name                 string
+		instances            []*ec2.Instance
+		expectations         func(*mockaws.MockClientMockRecorder)
+		expectedTerminations []*ec2.InstanceStateChange
+		expectError          bool
+	}{
+		{
+			name:      "All instances terminated in a single request",
+			instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameRunning), instance("i-2", ec2.InstanceStateNameRunning)},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1", "i-2"})}).
+					Return(&ec2.TerminateInstancesOutput{TerminatingInstances: []*ec2.InstanceStateChange{terminatingState("i-1"), terminatingState("i-2")}}, nil)
+			},
+			expectedTerminations: []*ec2.InstanceStateChange{terminatingState("i-1"), terminatingState("i-2")},
+		},
+		{
+			name:      "One of the batch is already gone",
+			instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameRunning), instance("i-2", ec2.InstanceStateNameRunning)},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1", "i-2"})}).
+					Return(nil, notFoundErr)
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1"})}).
+					Return(&ec2.TerminateInstancesOutput{TerminatingInstances: []*ec2.InstanceStateChange{terminatingState("i-1")}}, nil)
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-2"})}).
+					Return(nil, notFoundErr)
+			},
+			expectedTerminations: []*ec2.InstanceStateChange{terminatingState("i-1")},
+		},
+		{
+			name:      "Already terminated instances are skipped",
+			instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameRunning), instance("i-2", ec2.InstanceStateNameTerminated)},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1"})}).
+					Return(&ec2.TerminateInstancesOutput{TerminatingInstances: []*ec2.InstanceStateChange{terminatingState("i-1")}}, nil)
+			},
+			expectedTerminations: []*ec2.InstanceStateChange{terminatingState("i-1")},
+		},
+		{
+			name:         "Only terminated instances makes no request",
+			instances:    []*ec2.Instance{instance("i-1", ec2.InstanceStateNameTerminated)},
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+		},
+		{
+			name:      "Genuine failure is returned",
+			instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameRunning), instance("i-2", ec2.InstanceStateNameRunning)},
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1", "i-2"})}).
+					Return(nil, notFoundErr)
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1"})}).
+					Return(nil, fmt.Errorf("unauthorized"))
+				m.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-2"})}).
+					Return(nil, notFoundErr)
+			},
+			expectedTerminations: []*ec2.InstanceStateChange{},
+			expectError:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			terminations, err := terminateInstances(mockAWSClient, tc.instances)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if !equality.Semantic.DeepEqual(terminations, tc.expectedTerminations) {
+				t.Errorf("expected: %v, got: %v", tc.expectedTerminations, terminations)
+			}
+		})
+	}
+}
+
+func TestGetConsoleOutput(t *testing.T) {
+	testCases := []struct {
+		name           string
+		output         *ec2.GetConsoleOutputOutput
+		outputErr      error
+		expectedOutput string
+		expectedErr    error
+		expectError    bool
+	}{
+		{
+			name: "Console output is decoded",
+			output: &ec2.GetConsoleOutputOutput{
+				InstanceId: aws.String("i-1"),
+				Output:     aws.String(base64.StdEncoding.EncodeToString([]byte("Ignition: fetch failed\n"))),
+			},
+			expectedOutput: "Ignition: fetch failed\n",
+		},
+		{
+			name:        "Console output not yet available",
+			output:      &ec2.GetConsoleOutputOutput{InstanceId: aws.String("i-1")},
+			expectedErr: errConsoleOutputNotAvailable,
+			expectError: true,
+		},
+		{
+			name:        "Invalid encoding",
+			output:      &ec2.GetConsoleOutputOutput{InstanceId: aws.String("i-1"), Output: aws.String("not base64!")},
+			expectError: true,
+		},
+		{
+			name:        "API error",
+			outputErr:   fmt.Errorf("error"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().GetConsoleOutput(&ec2.GetConsoleOutputInput{InstanceId: aws.String("i-1")}).Return(tc.output, tc.outputErr)
+
+			output, err := getConsoleOutput(mockAWSClient, "i-1")
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got: %v", tc.expectedErr, err)
+			}
+			if output != tc.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tc.expectedOutput, output)
+			}
+		})
+	}
+}
+
+func TestDetectDuplicateNameTags(t *testing.T) {
+	instance := func(id, name string) *ec2.Instance {
+		i := &ec2.Instance{InstanceId: aws.String(id)}
+		if name != "" {
+			i.Tags = []*ec2.Tag{
+				{Key: aws.String("kubernetes.io/cluster/cluster-id"), Value: aws.String("owned")},
+				{Key: aws.String("Name"), Value: aws.String(name)},
+			}
+		}
+		return i
+	}
+
+	testCases := []struct {
+		name       string
+		instances  []*ec2.Instance
+		duplicates map[string][]string
+	}{
+		{
+			name:       "No instances",
+			duplicates: map[string][]string{},
+		},
+		{
+			name: "Only unique names",
+			instances: []*ec2.Instance{
+				instance("i-1", "machine-a"),
+				instance("i-2", "machine-b"),
+			},
+			duplicates: map[string][]string{},
+		},
+		{
+			name: "Duplicates and uniques mixed",
+			instances: []*ec2.Instance{
+				instance("i-1", "machine-a"),
+				instance("i-2", "machine-b"),
+				instance("i-3", "machine-a"),
+				instance("i-4", "machine-c"),
+				instance("i-5", "machine-c"),
+				instance("i-6", "machine-c"),
+				instance("i-7", ""),
+				instance("i-8", ""),
+			},
+			duplicates: map[string][]string{
+				"machine-a": {"i-1", "i-3"},
+				"machine-c": {"i-4", "i-5", "i-6"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			duplicates := detectDuplicateNameTags(tc.instances)
+			if !equality.Semantic.DeepEqual(duplicates, tc.duplicates) {
+				t.Errorf("expected: %v, got: %v", tc.duplicates, duplicates)
+			}
+		})
+	}
+}
+
+func TestGetInstanceByID(t *testing.T) {
+	reservation := func(id string) *ec2.Reservation {
+		return &ec2.Reservation{
+			Instances: []*ec2.Instance{
+				{
+					InstanceId: aws.String(id),
+					State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		output           *ec2.DescribeInstancesOutput
+		outputErr        error
+		expectNotFound   bool
+		expectError      bool
+		expectedInstance string
+	}{
+		{
+			name:           "Zero reservations",
+			output:         &ec2.DescribeInstancesOutput{},
+			expectNotFound: true,
+			expectError:    true,
+		},
+		{
+			name:             "One reservation",
+			output:           &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation("i-1")}},
+			expectedInstance: "i-1",
+		},
+		{
+			name:        "Two reservations",
+			output:      &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation("i-1"), reservation("i-1")}},
+			expectError: true,
+		},
+		{
+			name:           "Unknown instance ID",
+			outputErr:      awserr.New(ec2InstanceNotFoundErrorCode, "The instance ID 'i-1' does not exist", nil),
+			expectNotFound: true,
+			expectError:    true,
+		},
+		{
+			name:        "Other API error",
+			outputErr:   fmt.Errorf("error"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeInstances(stubDescribeInstancesInput("i-1")).Return(tc.output, tc.outputErr)
+
+			instance, err := getInstanceByID("i-1", mockAWSClient, existingInstanceStates())
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if tc.expectNotFound != errors.Is(err, ErrInstanceNotFound) {
+				t.Errorf("Expected not found error: %v, got: %v", tc.expectNotFound, err)
+			}
+			if tc.expectedInstance != "" && aws.StringValue(instance.InstanceId) != tc.expectedInstance {
+				t.Errorf("Expected instance %q, got: %v", tc.expectedInstance, instance)
+			}
+		})
+	}
+}
+
+func TestDescribeInstanceLifecycleState(t *testing.T) {
+	testCases := []struct {
+		name           string
+		instances      []*autoscaling.InstanceDetails
+		describeErr    error
+		expectedState  string
+		expectedWarmed bool
+		expectedError  bool
+	}{
+		{
+			name: "In service",
+			instances: []*autoscaling.InstanceDetails{
+				{InstanceId: aws.String("i-1"), LifecycleState: aws.String(autoscaling.LifecycleStateInService)},
+			},
+			expectedState: autoscaling.LifecycleStateInService,
+		},
+		{
+			name: "Stopped in warm pool",
+			instances: []*autoscaling.InstanceDetails{
+				{InstanceId: aws.String("i-1"), LifecycleState: aws.String(autoscaling.LifecycleStateWarmedStopped)},
+			},
+			expectedState:  autoscaling.LifecycleStateWarmedStopped,
+			expectedWarmed: true,
+		},
+		{
+			name: "Running in warm pool",
+			instances: []*autoscaling.InstanceDetails{
+				{InstanceId: aws.String("i-1"), LifecycleState: aws.String(autoscaling.LifecycleStateWarmedRunning)},
+			},
+			expectedState:  autoscaling.LifecycleStateWarmedRunning,
+			expectedWarmed: true,
+		},
+		{
+			name: "Not managed by an auto scaling group",
+		},
+		{
+			name:          "API error",
+			describeErr:   fmt.Errorf("error"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+				InstanceIds: aws.StringSlice([]string{"i-1"}),
+			}).Return(&autoscaling.DescribeAutoScalingInstancesOutput{AutoScalingInstances: tc.instances}, tc.describeErr)
+
+			state, err := describeInstanceLifecycleState(mockAWSClient, "i-1")
+			if tc.expectedError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+			if state != tc.expectedState {
+				t.Errorf("Expected state %q, got %q", tc.expectedState, state)
+			}
+			if isWarmPoolLifecycleState(state) != tc.expectedWarmed {
+				t.Errorf("Expected warm pool state: %v, got: %v", tc.expectedWarmed, isWarmPoolLifecycleState(state))
+			}
+		})
+	}
+}
+
+func TestProtectInstanceFromScaleIn(t *testing.T) {
+	testCases := []struct {
+		name          string
+		instances     []*autoscaling.InstanceDetails
+		describeErr   error
+		expectProtect bool
+		protectErr    error
+		expectedError bool
+	}{
+		{
+			name: "Managed instance in an auto scaling group",
+			instances: []*autoscaling.InstanceDetails{
+				{InstanceId: aws.String("i-1"), AutoScalingGroupName: aws.String("asg-1"), ProtectedFromScaleIn: aws.Bool(false)},
+			},
+			expectProtect: true,
+		},
+		{
+			name: "Already protected",
+			instances: []*autoscaling.InstanceDetails{
+				{InstanceId: aws.String("i-1"), AutoScalingGroupName: aws.String("asg-1"), ProtectedFromScaleIn: aws.Bool(true)},
+			},
+		},
+		{
+			name: "Not managed by an auto scaling group",
+		},
+		{
+			name: "Protection error",
+			instances: []*autoscaling.InstanceDetails{
+				{InstanceId: aws.String("i-1"), AutoScalingGroupName: aws.String("asg-1")},
+			},
+			expectProtect: true,
+			protectErr:    fmt.Errorf("error"),
+			expectedError: true,
+		},
+		{
+			name:          "Describe error",
+			describeErr:   fmt.Errorf("error"),
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+				InstanceIds: aws.StringSlice([]string{"i-1"}),
+			}).Return(&autoscaling.DescribeAutoScalingInstancesOutput{AutoScalingInstances: tc.instances}, tc.describeErr)
+			if tc.expectProtect {
+				mockAWSClient.EXPECT().SetInstanceProtection(&autoscaling.SetInstanceProtectionInput{
+					AutoScalingGroupName: aws.String("asg-1"),
+					InstanceIds:          aws.StringSlice([]string{"i-1"}),
+					ProtectedFromScaleIn: aws.Bool(true),
+				}).Return(&autoscaling.SetInstanceProtectionOutput{}, tc.protectErr)
+			}
+
+			err := protectInstanceFromScaleIn(mockAWSClient, "i-1")
+			if tc.expectedError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestWaitForInstanceTerminated(t *testing.T) {
+	defaultPollInterval := instanceTerminationPollInterval
+	instanceTerminationPollInterval = time.Millisecond
+	defer func() { instanceTerminationPollInterval = defaultPollInterval }()
+
+	output := func(state string) *ec2.DescribeInstancesOutput {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{
+				{
+					Instances: []*ec2.Instance{
+						{
+							InstanceId: aws.String("i-1"),
+							State:      &ec2.InstanceState{Name: aws.String(state)},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		expectations  func(*mockaws.MockClientMockRecorder)
+		timeout       time.Duration
+		expectedError string
+	}{
+		{
+			name: "Shutting down then terminated",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				gomock.InOrder(
+					m.DescribeInstances(stubDescribeInstancesInput("i-1")).Return(output(ec2.InstanceStateNameShuttingDown), nil).Times(2),
+					m.DescribeInstances(stubDescribeInstancesInput("i-1")).Return(output(ec2.InstanceStateNameTerminated), nil),
+				)
+			},
+			timeout: time.Second,
+		},
+		{
+			name: "Instance no longer exists",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeInstances(stubDescribeInstancesInput("i-1")).Return(&ec2.DescribeInstancesOutput{}, nil)
+			},
+			timeout: time.Second,
+		},
+		{
+			name: "Timeout",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeInstances(stubDescribeInstancesInput("i-1")).Return(output(ec2.InstanceStateNameShuttingDown), nil).AnyTimes()
+			},
+			timeout:       20 * time.Millisecond,
+			expectedError: "timed out waiting for instance i-1 to terminate, last observed state: shutting-down",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := waitForInstanceTerminated(mockAWSClient, "i-1", tc.timeout)
+			if tc.expectedError == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tc.expectedError != "" && (err == nil || err.Error() != tc.expectedError) {
+				t.Errorf("Expected error %q, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestGetInstanceByPrivateDNS(t *testing.T) {
+	privateDNS := "ip-10-0-0-1.ec2.internal"
+	instance := func(id, state string) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId:     aws.String(id),
+			PrivateDnsName: aws.String(privateDNS),
+			State:          &ec2.InstanceState{Name: aws.String(state)},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		reservations     []*ec2.Reservation
+		expectNotFound   bool
+		expectError      bool
+		expectedInstance string
+	}{
+		{
+			name:             "One matching instance",
+			reservations:     []*ec2.Reservation{{Instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameRunning)}}},
+			expectedInstance: "i-1",
+		},
+		{
+			name: "Terminated instance is ignored",
+			reservations: []*ec2.Reservation{
+				{Instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameTerminated)}},
+				{Instances: []*ec2.Instance{instance("i-2", ec2.InstanceStateNameRunning)}},
+			},
+			expectedInstance: "i-2",
+		},
+		{
+			name:           "No matching instance",
+			expectNotFound: true,
+			expectError:    true,
+		},
+		{
+			name: "Multiple matching instances",
+			reservations: []*ec2.Reservation{
+				{Instances: []*ec2.Instance{instance("i-1", ec2.InstanceStateNameRunning)}},
+				{Instances: []*ec2.Instance{instance("i-2", ec2.InstanceStateNameStopped)}},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+				Filters: []*ec2.Filter{
+					{
+						Name:   aws.String("private-dns-name"),
+						Values: aws.StringSlice([]string{privateDNS}),
+					},
+				},
+			}).Return(&ec2.DescribeInstancesOutput{Reservations: tc.reservations}, nil)
+
+			instance, err := getInstanceByPrivateDNS(mockAWSClient, privateDNS, existingInstanceStates())
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if tc.expectNotFound != errors.Is(err, ErrInstanceNotFound) {
+				t.Errorf("Expected not found error: %v, got: %v", tc.expectNotFound, err)
+			}
+			if tc.expectedInstance != "" && aws.StringValue(instance.InstanceId) != tc.expectedInstance {
+				t.Errorf("Expected instance %q, got: %v", tc.expectedInstance, instance)
+			}
+		})
+	}
+}
+
+func TestComputeTagChanges(t *testing.T) {
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+	current := []*ec2.Tag{tag("Name", "machine"), tag("team", "infra"), tag("stale", "value"), tag("padded", "value")}
+
+	testCases := []struct {
+		name             string
+		desired          map[string]string
+		stale            []string
+		expectedAdd      []*ec2.Tag
+		expectedDeletion []string
+	}{
+		{
+			name:             "Everything already correct",
+			desired:          map[string]string{"Name": "machine", "team": "infra", "padded": " value "},
+			expectedAdd:      []*ec2.Tag{},
+			expectedDeletion: []string{},
+		},
+		{
+			name:             "Only the changed tag is added",
+			desired:          map[string]string{"Name": "machine", "team": "platform", "owner": "me"},
+			expectedAdd:      []*ec2.Tag{tag("owner", "me"), tag("team", "platform")},
+			expectedDeletion: []string{},
+		},
+		{
+			name:             "Only present stale tags are deleted",
+			desired:          map[string]string{"Name": "machine"},
+			stale:            []string{"stale", "missing", "Name", "stale"},
+			expectedAdd:      []*ec2.Tag{},
+			expectedDeletion: []string{"stale"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			toAdd, toDelete := computeTagChanges(current, tc.desired, tc.stale)
+			if !equality.Semantic.DeepEqual(toAdd, tc.expectedAdd) {
+				t.Errorf("Expected tags to add %v, got %v", tc.expectedAdd, toAdd)
+			}
+			if !equality.Semantic.DeepEqual(toDelete, tc.expectedDeletion) {
+				t.Errorf("Expected tags to delete %v, got %v", tc.expectedDeletion, toDelete)
+			}
+		})
+	}
+}
+
+func TestApplyTagChanges(t *testing.T) {
+	tagsToAdd := []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("infra")}}
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+		Tags:       []*ec2.Tag{{Key: aws.String("stale"), Value: aws.String("old")}},
+	}
+	describeOutput := func(staleValue string) *ec2.DescribeInstancesOutput {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+				InstanceId: aws.String("i-1"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				Tags:       []*ec2.Tag{{Key: aws.String("stale"), Value: aws.String(staleValue)}},
+			}}}},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		tagsToAdd    []*ec2.Tag
+		keysToDelete []string
+		expectCreate bool
+		current      *ec2.DescribeInstancesOutput
+		expectDelete bool
+	}{
+		{
+			name: "No changes makes no API calls",
+		},
+		{
+			name:         "Additions only",
+			tagsToAdd:    tagsToAdd,
+			expectCreate: true,
+		},
+		{
+			name:         "Additions and deletions",
+			tagsToAdd:    tagsToAdd,
+			keysToDelete: []string{"stale"},
+			expectCreate: true,
+			current:      describeOutput("old"),
+			expectDelete: true,
+		},
+		{
+			name:         "Deletion skipped when the tag was rewritten",
+			keysToDelete: []string{"stale"},
+			current:      describeOutput("new"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectCreate {
+				mockAWSClient.EXPECT().CreateTags(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{"i-1"}),
+					Tags:      tc.tagsToAdd,
+				}).Return(&ec2.CreateTagsOutput{}, nil).Times(1)
+			}
+			if tc.current != nil {
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{"i-1"}),
+				}).Return(tc.current, nil).Times(1)
+			}
+			if tc.expectDelete {
+				mockAWSClient.EXPECT().DeleteTags(&ec2.DeleteTagsInput{
+					Resources: aws.StringSlice([]string{"i-1"}),
+					Tags:      []*ec2.Tag{{Key: aws.String("stale")}},
+				}).Return(&ec2.DeleteTagsOutput{}, nil).Times(1)
+			}
+
+			if err := applyTagChanges(mockAWSClient, instance, tc.tagsToAdd, tc.keysToDelete); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCorrectExistingTagsNoChanges(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	clusterID, _ := getClusterID(machine)
+	instance := &ec2.Instance{
+		InstanceId: aws.String(stubInstanceID),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("Name"), Value: aws.String(machine.Name)},
+			{Key: aws.String("kubernetes.io/cluster/" + clusterID), Value: aws.String("owned")},
+			{Key: aws.String("team"), Value: aws.String("infra")},
+		},
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// No expectations: any API call fails the test.
+	mockAWSClient := mockaws.NewMockClient(mockCtrl)
+
+	tags := map[string]string{"team": "infra"}
+	if err := correctExistingTags(machine, instance, mockAWSClient, tags); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(tags) != 1 {
+		t.Errorf("Expected the tags to be left untouched, got: %v", tags)
+	}
+}
+
+func TestDeleteInstanceTags(t *testing.T) {
+	testCases := []struct {
+		name         string
+		keys         []string
+		expectDelete bool
+		deleteErr    error
+		expectError  bool
+	}{
+		{
+			name:         "Delete by key only",
+			keys:         []string{"team", "cost-center"},
+			expectDelete: true,
+		},
+		{
+			name: "Nothing to delete",
+		},
+		{
+			name:         "API error",
+			keys:         []string{"team"},
+			expectDelete: true,
+			deleteErr:    fmt.Errorf("error"),
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectDelete {
+				mockAWSClient.EXPECT().DeleteTags(gomock.Any()).DoAndReturn(func(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+					if len(input.Tags) != len(tc.keys) {
+						t.Errorf("Expected %d tags, got %d", len(tc.keys), len(input.Tags))
+					}
+					for _, tag := range input.Tags {
+						if tag.Value != nil {
+							t.Errorf("Expected tag %q to be deleted by key only, got value %q", aws.StringValue(tag.Key), *tag.Value)
+						}
+					}
+					return &ec2.DeleteTagsOutput{}, tc.deleteErr
+				})
+			}
+
+			err := deleteInstanceTags(mockAWSClient, "i-1", tc.keys)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestDeleteStaleInstanceTags(t *testing.T) {
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+	instance := &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+		Tags:       []*ec2.Tag{tag("team", "infra"), tag("owner", "alice"), tag("cost-center", "42")},
+	}
+	describeOutput := func(tags ...*ec2.Tag) *ec2.DescribeInstancesOutput {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+				InstanceId: aws.String("i-1"),
+				State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				Tags:       tags,
+			}}}},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		keys         []string
+		current      *ec2.DescribeInstancesOutput
+		describeErr  error
+		expectedKeys []string
+		expectError  bool
+	}{
+		{
+			name:         "Tags unchanged since described",
+			keys:         []string{"team", "owner"},
+			current:      describeOutput(tag("team", "infra"), tag("owner", "alice"), tag("cost-center", "42")),
+			expectedKeys: []string{"owner", "team"},
+		},
+		{
+			name:         "Tag rewritten between describe and delete",
+			keys:         []string{"team", "owner"},
+			current:      describeOutput(tag("team", "workers"), tag("owner", "alice"), tag("cost-center", "42")),
+			expectedKeys: []string{"owner"},
+		},
+		{
+			name:         "Tag reappeared between describe and delete",
+			keys:         []string{"team", "env"},
+			current:      describeOutput(tag("team", "infra"), tag("env", "prod")),
+			expectedKeys: []string{"team"},
+		},
+		{
+			name:    "Tags already removed",
+			keys:    []string{"team", "owner"},
+			current: describeOutput(tag("cost-center", "42")),
+		},
+		{
+			name:        "Describe error",
+			keys:        []string{"team"},
+			describeErr: fmt.Errorf("error"),
+			expectError: true,
+		},
+		{
+			name: "No keys makes no API calls",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if len(tc.keys) > 0 {
+				mockAWSClient.EXPECT().DescribeInstances(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{"i-1"}),
+				}).Return(tc.current, tc.describeErr)
+			}
+			if len(tc.expectedKeys) > 0 {
+				tags := []*ec2.Tag{}
+				for _, key := range tc.expectedKeys {
+					tags = append(tags, &ec2.Tag{Key: aws.String(key)})
+				}
+				mockAWSClient.EXPECT().DeleteTags(&ec2.DeleteTagsInput{
+					Resources: aws.StringSlice([]string{"i-1"}),
+					Tags:      tags,
+				}).Return(&ec2.DeleteTagsOutput{}, nil)
+			}
+
+			err := deleteStaleInstanceTags(mockAWSClient, instance, tc.keys)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestGetInstanceByProviderID(t *testing.T) {
+	testCases := []struct {
+		name             string
+		providerID       string
+		expectDescribe   bool
+		expectError      bool
+		expectedInstance string
+	}{
+		{
+			name:             "Valid providerID",
+			providerID:       "aws:///us-east-1a/i-1",
+			expectDescribe:   true,
+			expectedInstance: "i-1",
+		},
+		{
+			name:             "Valid providerID without availability zone",
+			providerID:       "aws:////i-1",
+			expectDescribe:   true,
+			expectedInstance: "i-1",
+		},
+		{
+			name:        "Wrong scheme",
+			providerID:  "gce:///us-east-1a/i-1",
+			expectError: true,
+		},
+		{
+			name:        "Missing instance ID",
+			providerID:  "aws:///us-east-1a/",
+			expectError: true,
+		},
+		{
+			name:        "Too many segments",
+			providerID:  "aws:///us-east-1/us-east-1a/i-1",
+			expectError: true,
+		},
+		{
+			name:        "Empty providerID",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectDescribe {
+				mockAWSClient.EXPECT().DescribeInstances(stubDescribeInstancesInput("i-1")).Return(&ec2.DescribeInstancesOutput{
+					Reservations: []*ec2.Reservation{
+						{
+							Instances: []*ec2.Instance{
+								{
+									InstanceId: aws.String("i-1"),
+									State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+								},
+							},
+						},
+					},
+				}, nil)
+			}
+
+			instance, err := getInstanceByProviderID(tc.providerID, mockAWSClient, existingInstanceStates())
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if tc.expectedInstance != "" && aws.StringValue(instance.InstanceId) != tc.expectedInstance {
+				t.Errorf("Expected instance %q, got: %v", tc.expectedInstance, instance)
+			}
+		})
+	}
+}
+
+func TestGetInstanceWithState(t *testing.T) {
+	testCases := []struct {
+		state            string
+		expectedTerminal bool
+	}{
+		{state: ec2.InstanceStateNameRunning},
+		{state: ec2.InstanceStateNameStopped},
+		{state: ec2.InstanceStateNameShuttingDown, expectedTerminal: true},
+		{state: ec2.InstanceStateNameTerminated, expectedTerminal: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.state, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeInstances(stubDescribeInstancesInput("i-1")).
+				Return(stubDescribeInstancesOutput("ami-a9acbbd6", "i-1", tc.state, "192.168.0.10"), nil)
+
+			instance, terminal, err := getInstanceWithState("i-1", mockAWSClient)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if aws.StringValue(instance.InstanceId) != "i-1" {
+				t.Errorf("Expected instance i-1, got: %v", instance)
+			}
+			if terminal != tc.expectedTerminal {
+				t.Errorf("Expected terminal: %v, got: %v", tc.expectedTerminal, terminal)
+			}
+		})
+	}
+}
+
+func TestConditionSuccess(t *testing.T) {
+	launchTime := time.Date(2022, time.March, 4, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		instance        *ec2.Instance
+		expectedMessage string
+	}{
+		{
+			name:            "With launch time",
+			instance:        &ec2.Instance{LaunchTime: &launchTime},
+			expectedMessage: "Machine successfully created, instance launched at 2022-03-04T10:30:00Z",
+		},
+		{
+			name:            "Without launch time",
+			instance:        &ec2.Instance{},
+			expectedMessage: "Machine successfully created",
+		},
+		{
+			name:            "Without instance",
+			expectedMessage: "Machine successfully created",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			condition := conditionSuccess(tc.instance)
+			if condition.Status != corev1.ConditionTrue || condition.Reason != machinev1.MachineCreationSucceededConditionReason {
+				t.Errorf("Expected a successful MachineCreation condition, got %+v", condition)
+			}
+			if condition.Message != tc.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestLaunchFailedCondition(t *testing.T) {
+	machine, err := stubMachine()
+	if err != nil {
+		t.Fatalf("Unable to build test machine manifest: %v", err)
+	}
+	providerConfig := stubProviderConfig()
+
+	testCases := []struct {
+		name            string
+		runInstancesErr error
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "Instance limit exceeded",
+			runInstancesErr: awserr.NewRequestFailure(awserr.New("InstanceLimitExceeded", "Your quota allows for 0 more running instance(s).", nil), 400, "request-id"),
+			expectedReason:  QuotaExceededConditionReason,
+			expectedMessage: "InstanceLimitExceeded: error launching instance: Your quota allows for 0 more running instance(s).",
+		},
+		{
+			name:            "vCPU limit exceeded",
+			runInstancesErr: awserr.NewRequestFailure(awserr.New("VcpuLimitExceeded", "You have requested more vCPU capacity than your current vCPU limit allows.", nil), 400, "request-id"),
+			expectedReason:  QuotaExceededConditionReason,
+			expectedMessage: "VcpuLimitExceeded: error launching instance: You have requested more vCPU capacity than your current vCPU limit allows.",
+		},
+		{
+			name:            "Configuration error",
+			runInstancesErr: awserr.NewRequestFailure(awserr.New("InvalidParameterValue", "Invalid value for instance type.", nil), 400, "request-id"),
+			expectedReason:  machinev1.MachineCreationFailedConditionReason,
+			expectedMessage: "error launching instance: Invalid value for instance type.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeSecurityGroups(gomock.Any()).Return(&ec2.DescribeSecurityGroupsOutput{}, nil).AnyTimes()
+			mockAWSClient.EXPECT().RunInstances(gomock.Any()).Return(nil, tc.runInstancesErr)
+
+			_, launchErr := launchInstance(machine, providerConfig, nil, mockAWSClient, nil)
+			if launchErr == nil {
+				t.Fatal("Expected launchInstance to fail")
+			}
+
+			condition := launchFailedCondition(launchErr)
+			if condition.Type != machinev1.MachineCreation || condition.Status != corev1.ConditionFalse {
+				t.Errorf("Expected a failed MachineCreation condition, got %+v", condition)
+			}
+			if condition.Reason != tc.expectedReason {
+				t.Errorf("Expected reason %q, got %q", tc.expectedReason, condition.Reason)
+			}
+			if condition.Message != tc.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestIsTerminalError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		terminal bool
+	}{
+		{name: "Invalid parameter value", err: awserr.New("InvalidParameterValue", "", nil), terminal: true},
+		{name: "AMI not found", err: awserr.New("InvalidAMIID.NotFound", "", nil), terminal: true},
+		{name: "Subnet not found", err: awserr.New("InvalidSubnetID.NotFound", "", nil), terminal: true},
+		{name: "Unauthorized", err: awserr.NewRequestFailure(awserr.New("UnauthorizedOperation", "", nil), 403, "request-id"), terminal: true},
+		{name: "Classic load balancer not found", err: awserr.New(elb.ErrCodeAccessPointNotFoundException, "", nil), terminal: true},
+		{name: "Wrapped target group not found", err: fmt.Errorf("error registering: %w", awserr.New(elbv2.ErrCodeTargetGroupNotFoundException, "", nil)), terminal: true},
+		{name: "Throttling", err: awserr.New("RequestLimitExceeded", "", nil)},
+		{name: "Insufficient capacity", err: awserr.New("InsufficientInstanceCapacity", "", nil)},
+		{name: "Instance quota", err: awserr.New("InstanceLimitExceeded", "", nil)},
+		{name: "Server error", err: awserr.NewRequestFailure(awserr.New("InternalError", "", nil), 500, "request-id")},
+		{name: "Non AWS error", err: fmt.Errorf("InvalidParameterValue")},
+		{name: "Nil error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if terminal := isTerminalError(tc.err); terminal != tc.terminal {
+				t.Errorf("Expected terminal: %v, got: %v", tc.terminal, terminal)
+			}
+		})
+	}
+}
@@ -0,0 +1,959 @@
+package machine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxUserDataSize is the maximum size in bytes of the base64 encoded user data accepted by EC2
+	maxUserDataSize = 16384
+
+	// launchTemplateVersionLatest and launchTemplateVersionDefault are the version aliases accepted by EC2
+	launchTemplateVersionLatest  = "$Latest"
+	launchTemplateVersionDefault = "$Default"
+
+	// partitionCapacityWarningPercent is the share of the instance cap of a partition from which a warning is logged.
+	partitionCapacityWarningPercent = 90
+)
+
+// enclaveSupportedInstanceFamilies lists the instance families that support AWS Nitro Enclaves.
+// Within these families only sizes with at least 4 vCPUs, i.e. xlarge and above, are supported.
+// Bare metal sizes are not supported.
+var enclaveSupportedInstanceFamilies = []string{
+	"c5", "c5a", "c5ad", "c5d", "c5n", "c6g", "c6gd", "c6gn", "c6i",
+	"m5", "m5a", "m5ad", "m5d", "m5dn", "m5n", "m5zn", "m6g", "m6gd", "m6i",
+	"r5", "r5a", "r5ad", "r5b", "r5d", "r5dn", "r5n", "r6g", "r6gd", "r6i",
+}
+
+// enclaveUnsupportedInstanceSizes are the sizes with fewer than 4 vCPUs, or bare metal,
+// that do not support AWS Nitro Enclaves.
+var enclaveUnsupportedInstanceSizes = []string{"nano", "micro", "small", "medium", "large", "metal"}
+
+// maxNetworkInterfacesByInstanceType is the maximum number of network interfaces
+// that can be attached to instances of the common instance types.
+var maxNetworkInterfacesByInstanceType = map[string]int{
+	"t3.micro": 2, "t3.small": 3, "t3.medium": 3, "t3.large": 3, "t3.xlarge": 4, "t3.2xlarge": 4,
+	"m4.large": 2, "m4.xlarge": 4, "m4.2xlarge": 4, "m4.4xlarge": 8, "m4.10xlarge": 8, "m4.16xlarge": 8,
+	"m5.large": 3, "m5.xlarge": 4, "m5.2xlarge": 4, "m5.4xlarge": 8, "m5.8xlarge": 8, "m5.12xlarge": 8, "m5.16xlarge": 15, "m5.24xlarge": 15,
+	"m6i.large": 3, "m6i.xlarge": 4, "m6i.2xlarge": 4, "m6i.4xlarge": 8, "m6i.8xlarge": 8, "m6i.12xlarge": 8, "m6i.16xlarge": 15, "m6i.24xlarge": 15, "m6i.32xlarge": 15,
+	"c5.large": 3, "c5.xlarge": 4, "c5.2xlarge": 4, "c5.4xlarge": 8, "c5.9xlarge": 8, "c5.12xlarge": 8, "c5.18xlarge": 15, "c5.24xlarge": 15,
+	"r5.large": 3, "r5.xlarge": 4, "r5.2xlarge": 4, "r5.4xlarge": 8, "r5.8xlarge": 8, "r5.12xlarge": 8, "r5.16xlarge": 15, "r5.24xlarge": 15,
+}
+
+// ipv4AddressesPerInterfaceByInstanceType is the maximum number of private IPv4 addresses per network interface
+// of the instance types listed in maxNetworkInterfacesByInstanceType.
+var ipv4AddressesPerInterfaceByInstanceType = map[string]int{
+	"t3.micro": 2, "t3.small": 4, "t3.medium": 6, "t3.large": 12, "t3.xlarge": 15, "t3.2xlarge": 15,
+	"m4.large": 10, "m4.xlarge": 15, "m4.2xlarge": 15, "m4.4xlarge": 30, "m4.10xlarge": 30, "m4.16xlarge": 30,
+	"m5.large": 10, "m5.xlarge": 15, "m5.2xlarge": 15, "m5.4xlarge": 30, "m5.8xlarge": 30, "m5.12xlarge": 30, "m5.16xlarge": 50, "m5.24xlarge": 50,
+	"m6i.large": 10, "m6i.xlarge": 15, "m6i.2xlarge": 15, "m6i.4xlarge": 30, "m6i.8xlarge": 30, "m6i.12xlarge": 30, "m6i.16xlarge": 50, "m6i.24xlarge": 50, "m6i.32xlarge": 50,
+	"c5.large": 10, "c5.xlarge": 15, "c5.2xlarge": 15, "c5.4xlarge": 30, "c5.9xlarge": 30, "c5.12xlarge": 30, "c5.18xlarge": 50, "c5.24xlarge": 50,
+	"r5.large": 10, "r5.xlarge": 15, "r5.2xlarge": 15, "r5.4xlarge": 30, "r5.8xlarge": 30, "r5.12xlarge": 30, "r5.16xlarge": 50, "r5.24xlarge": 50,
+}
+
+// instanceStoreVolumesByInstanceType is the number of instance store volumes of the common instance types
+// that have them. The instance types listed in maxNetworkInterfacesByInstanceType have none.
+var instanceStoreVolumesByInstanceType = map[string]int{
+	"m5d.large": 1, "m5d.xlarge": 1, "m5d.2xlarge": 1, "m5d.4xlarge": 2, "m5d.8xlarge": 2, "m5d.12xlarge": 2, "m5d.16xlarge": 4, "m5d.24xlarge": 4,
+	"c5d.large": 1, "c5d.xlarge": 1, "c5d.2xlarge": 1, "c5d.4xlarge": 1, "c5d.9xlarge": 1, "c5d.12xlarge": 2, "c5d.18xlarge": 2, "c5d.24xlarge": 4,
+	"r5d.large": 1, "r5d.xlarge": 1, "r5d.2xlarge": 1, "r5d.4xlarge": 2, "r5d.8xlarge": 2, "r5d.12xlarge": 2, "r5d.16xlarge": 4, "r5d.24xlarge": 4,
+	"i3.large": 1, "i3.xlarge": 1, "i3.2xlarge": 1, "i3.4xlarge": 2, "i3.8xlarge": 4, "i3.16xlarge": 8,
+}
+
+// gpuInstanceFamilies lists the instance families that provide GPUs.
+var gpuInstanceFamilies = []string{"g3", "g3s", "g4ad", "g4dn", "g5", "g5g", "g6", "gr6", "p2", "p3", "p3dn", "p4d", "p4de", "p5"}
+
+// acceleratorInstanceFamilies lists the instance families that provide non GPU accelerators:
+// Inferentia, Trainium, Gaudi, FPGAs and video transcoding accelerators.
+var acceleratorInstanceFamilies = []string{"dl1", "f1", "inf1", "inf2", "trn1", "trn1n", "vt1"}
+
+// singleTenantUnsupportedInstanceFamilies lists the previous generation burstable instance families
+// that cannot run as Dedicated Instances nor on Dedicated Hosts.
+var singleTenantUnsupportedInstanceFamilies = []string{"t1", "t2"}
+
+// hostTenancyOnlyInstanceFamilies lists the instance families that can only run on Dedicated Hosts.
+var hostTenancyOnlyInstanceFamilies = []string{"mac1", "mac2", "mac2-m2", "mac2-m2pro"}
+
+// ebsOptimizedUnsupportedInstanceFamilies lists the previous generation instance families that do not support EBS optimization.
+var ebsOptimizedUnsupportedInstanceFamilies = []string{"t1", "t2", "cc2", "cr1", "hs1"}
+
+// ebsOptimizedUnsupportedInstanceTypes lists the instance types that do not support EBS optimization
+// although other sizes of their family do.
+var ebsOptimizedUnsupportedInstanceTypes = []string{
+	"m1.small", "m1.medium", "m3.medium", "m3.large", "c3.8xlarge", "r3.8xlarge", "i2.8xlarge", "g2.8xlarge",
+}
+
+// ebsOptimizedOptionalInstanceFamilies lists the previous generation instance families on which EBS optimization
+// has to be enabled explicitly. It is enabled by default, and cannot be disabled, on all the other instance types
+// that support it.
+var ebsOptimizedOptionalInstanceFamilies = []string{"c1", "c3", "g2", "i2", "m1", "m2", "m3", "r3"}
+
+// volumeIOPSLimit is the range of provisioned IOPS a volume type accepts and the maximum ratio of IOPS to volume size.
+type volumeIOPSLimit struct {
+	minIOPS       int64
+	maxIOPS       int64
+	maxIOPSPerGiB int64
+}
+
+// volumeIOPSLimits lists the provisioned IOPS limits of the volume types that accept an IOPS value.
+// The io2 limits are the ones of io2 volumes not attached to instances built on the Nitro System,
+// where Block Express raises them to 256000 IOPS and 1000 IOPS per GiB.
+var volumeIOPSLimits = map[string]volumeIOPSLimit{
+	ec2.VolumeTypeGp3: {minIOPS: 3000, maxIOPS: 16000, maxIOPSPerGiB: 500},
+	ec2.VolumeTypeIo1: {minIOPS: 100, maxIOPS: 64000, maxIOPSPerGiB: 50},
+	ec2.VolumeTypeIo2: {minIOPS: 100, maxIOPS: 64000, maxIOPSPerGiB: 500},
+}
+
+// availabilityZoneRegexp matches availability zone names, e.g. us-east-1a, us-gov-west-1b,
+// local zones such as us-east-1-bos-1a and wavelength zones such as us-east-1-wl1-bos-wlz-1.
+// The first submatch is the region of the zone.
+var availabilityZoneRegexp = regexp.MustCompile(`^([a-z]{2}(?:-gov|-iso[a-z]?)?-[a-z]+-[0-9]+)(?:[a-z]|-[a-z]+-[0-9]+[a-z]|-wl[0-9]+-[a-z]+-wlz-[0-9]+)$`)
+
+// normalizeAvailabilityZone lowercases the availability zone and validates its format.
+// An empty availability zone is valid and means that no zone was requested.
+func normalizeAvailabilityZone(az string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(az))
+	if normalized == "" {
+		return "", nil
+	}
+	if !availabilityZoneRegexp.MatchString(normalized) {
+		return "", fmt.Errorf("availability zone %q is not a valid zone name", az)
+	}
+	return normalized, nil
+}
+
+// regionFromAZ returns the region an availability zone belongs to, e.g. us-east-1 for
+// both us-east-1a and the local zone us-east-1-bos-1a.
+func regionFromAZ(az string) (string, error) {
+	matches := availabilityZoneRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(az)))
+	if matches == nil {
+		return "", fmt.Errorf("availability zone %q is not a valid zone name", az)
+	}
+	return matches[1], nil
+}
+
+// validateUserDataSize checks that the user data fits within the EC2 limit once base64 encoded.
+func validateUserDataSize(userData []byte) error {
+	if size := base64.StdEncoding.EncodedLen(len(userData)); size > maxUserDataSize {
+		return fmt.Errorf("user data is %d bytes once base64 encoded, which exceeds the limit of %d bytes", size, maxUserDataSize)
+	}
+	return nil
+}
+
+// validateLaunchTemplate checks that the referenced launch template and version exist.
+// The template can be referenced either by ID or by name, the ID has priority.
+// The $Latest and $Default version aliases are resolved against the template,
+// an empty version is treated as $Default.
+func validateLaunchTemplate(client awsclient.Client, templateID, templateName, version string) error {
+	describeTemplatesRequest := &ec2.DescribeLaunchTemplatesInput{}
+	templateRef := templateID
+	switch {
+	case templateID != "":
+		describeTemplatesRequest.LaunchTemplateIds = aws.StringSlice([]string{templateID})
+	case templateName != "":
+		templateRef = templateName
+		describeTemplatesRequest.LaunchTemplateNames = aws.StringSlice([]string{templateName})
+	default:
+		return fmt.Errorf("launch template ID or name need to be specified")
+	}
+
+	describeTemplatesResult, err := client.DescribeLaunchTemplates(describeTemplatesRequest)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "InvalidLaunchTemplateId.NotFound", "InvalidLaunchTemplateName.NotFoundException":
+				return fmt.Errorf("launch template %q not found", templateRef)
+			}
+		}
+		klog.Errorf("Error describing launch template %q: %v", templateRef, err)
+		return fmt.Errorf("error describing launch template %q: %v", templateRef, err)
+	}
+	if len(describeTemplatesResult.LaunchTemplates) == 0 {
+		return fmt.Errorf("launch template %q not found", templateRef)
+	}
+	template := describeTemplatesResult.LaunchTemplates[0]
+
+	var versionNumber int64
+	switch version {
+	case "", launchTemplateVersionDefault:
+		versionNumber = aws.Int64Value(template.DefaultVersionNumber)
+	case launchTemplateVersionLatest:
+		versionNumber = aws.Int64Value(template.LatestVersionNumber)
+	default:
+		versionNumber, err = strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid launch template version %q: must be a number, %s or %s", version, launchTemplateVersionLatest, launchTemplateVersionDefault)
+		}
+	}
+
+	resolvedVersion := strconv.FormatInt(versionNumber, 10)
+	describeVersionsResult, err := client.DescribeLaunchTemplateVersions(&ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId: template.LaunchTemplateId,
+		Versions:         aws.StringSlice([]string{resolvedVersion}),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidLaunchTemplateId.VersionNotFound" {
+			return fmt.Errorf("version %s of launch template %q not found", resolvedVersion, templateRef)
+		}
+		klog.Errorf("Error describing version %s of launch template %q: %v", resolvedVersion, templateRef, err)
+		return fmt.Errorf("error describing version %s of launch template %q: %v", resolvedVersion, templateRef, err)
+	}
+	if len(describeVersionsResult.LaunchTemplateVersions) == 0 {
+		return fmt.Errorf("version %s of launch template %q not found", resolvedVersion, templateRef)
+	}
+
+	return nil
+}
+
+// validateKeyPair checks that the referenced SSH key pair exists.
+// An empty key name is valid and means that no key pair is attached to the instance.
+func validateKeyPair(client awsclient.Client, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	result, err := client.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		KeyNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidKeyPair.NotFound" {
+			return fmt.Errorf("key pair %q not found", name)
+		}
+		klog.Errorf("Error describing key pair %q: %v", name, err)
+		return fmt.Errorf("error describing key pair %q: %v", name, err)
+	}
+	if len(result.KeyPairs) == 0 {
+		return fmt.Errorf("key pair %q not found", name)
+	}
+
+	return nil
+}
+
+// validatePartitionNumber checks that the placement group uses the partition strategy and that the requested
+// partition, numbered from 1, is within the partition count of the group.
+func validatePartitionNumber(client awsclient.Client, groupName string, partitionNumber int64) error {
+	result, err := client.DescribePlacementGroups(&ec2.DescribePlacementGroupsInput{
+		GroupNames: aws.StringSlice([]string{groupName}),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidPlacementGroup.Unknown" {
+			return fmt.Errorf("placement group %q not found", groupName)
+		}
+		klog.Errorf("Error describing placement group %q: %v", groupName, err)
+		return fmt.Errorf("error describing placement group %q: %v", groupName, err)
+	}
+	if len(result.PlacementGroups) == 0 {
+		return fmt.Errorf("placement group %q not found", groupName)
+	}
+
+	group := result.PlacementGroups[0]
+	if strategy := aws.StringValue(group.Strategy); strategy != ec2.PlacementStrategyPartition {
+		return fmt.Errorf("placement group %q has strategy %q, a partition number requires the %q strategy", groupName, strategy, ec2.PlacementStrategyPartition)
+	}
+	if partitionCount := aws.Int64Value(group.PartitionCount); partitionNumber < 1 || partitionNumber > partitionCount {
+		return fmt.Errorf("partition number %d is out of range for placement group %q, valid partitions are 1 to %d", partitionNumber, groupName, partitionCount)
+	}
+
+	return nil
+}
+
+// validatePartitionCapacity checks that the partition of the placement group, numbered from 1, has room for
+// another instance. EC2 does not limit the number of instances in a partition, the cap is chosen by the caller,
+// e.g. to keep a single failure domain from holding a disproportionate share of the machines. The instances not
+// terminated yet are counted against maxInstances, and a warning is logged once the partition gets close to it.
+// A zero maxInstances means no cap.
+func validatePartitionCapacity(client awsclient.Client, groupName string, partitionNumber int64, maxInstances int) error {
+	if maxInstances <= 0 {
+		return nil
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("placement-group-name"), Values: aws.StringSlice([]string{groupName})},
+			{Name: aws.String("placement-partition-number"), Values: aws.StringSlice([]string{strconv.FormatInt(partitionNumber, 10)})},
+			{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{
+				ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped,
+			})},
+		},
+	}
+
+	count := 0
+	for {
+		result, err := client.DescribeInstances(input)
+		if err != nil {
+			klog.Errorf("Error describing instances in partition %d of placement group %q: %v", partitionNumber, groupName, err)
+			return fmt.Errorf("error describing instances in partition %d of placement group %q: %v", partitionNumber, groupName, err)
+		}
+		for _, reservation := range result.Reservations {
+			count += len(reservation.Instances)
+		}
+		if aws.StringValue(result.NextToken) == "" {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+
+	if count >= maxInstances {
+		return fmt.Errorf("partition %d of placement group %q already has %d instances, the limit is %d", partitionNumber, groupName, count, maxInstances)
+	}
+	if count*100 >= maxInstances*partitionCapacityWarningPercent {
+		klog.Warningf("Partition %d of placement group %q has %d instances, close to the limit of %d", partitionNumber, groupName, count, maxInstances)
+	}
+	return nil
+}
+
+// validateAMIArchitecture checks that the architecture of the AMI is one of the architectures supported by the instance type,
+// e.g. that an x86_64 AMI is not launched on a Graviton instance type.
+func validateAMIArchitecture(client awsclient.Client, amiID, instanceType string) error {
+	images, err := client.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{amiID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing AMI %s: %v", amiID, err)
+		return fmt.Errorf("error describing AMI %s: %v", amiID, err)
+	}
+	if len(images.Images) == 0 {
+		return fmt.Errorf("AMI %s not found", amiID)
+	}
+	architecture := aws.StringValue(images.Images[0].Architecture)
+
+	instanceTypes, err := client.DescribeInstanceTypes(&ec2.DescribeInstanceTypesInput{
+		InstanceTypes: aws.StringSlice([]string{instanceType}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing instance type %q: %v", instanceType, err)
+		return fmt.Errorf("error describing instance type %q: %v", instanceType, err)
+	}
+	if len(instanceTypes.InstanceTypes) == 0 || instanceTypes.InstanceTypes[0].ProcessorInfo == nil {
+		return fmt.Errorf("instance type %q not found", instanceType)
+	}
+	supported := aws.StringValueSlice(instanceTypes.InstanceTypes[0].ProcessorInfo.SupportedArchitectures)
+
+	if !stringSliceContains(supported, architecture) {
+		return fmt.Errorf("AMI %s has architecture %s, which instance type %q does not support, supported architectures are %s",
+			amiID, architecture, instanceType, strings.Join(supported, ", "))
+	}
+	return nil
+}
+
+// validateAMIVirtualization checks that the AMI uses hardware virtual machine virtualization.
+// Paravirtual AMIs can only run on previous generation instance types.
+func validateAMIVirtualization(client awsclient.Client, amiID string) error {
+	images, err := client.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{amiID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing AMI %s: %v", amiID, err)
+		return fmt.Errorf("error describing AMI %s: %v", amiID, err)
+	}
+	if len(images.Images) == 0 {
+		return fmt.Errorf("AMI %s not found", amiID)
+	}
+
+	if aws.StringValue(images.Images[0].VirtualizationType) == ec2.VirtualizationTypeParavirtual {
+		return fmt.Errorf("AMI %s uses %s virtualization, only %s AMIs are supported",
+			amiID, ec2.VirtualizationTypeParavirtual, ec2.VirtualizationTypeHvm)
+	}
+	return nil
+}
+
+// validateBlockDeviceNames checks that the block device mappings of the provider spec do not collide.
+// A mapping without device name targets the root device of the AMI, so a named mapping using the root
+// device name of the AMI would silently replace the root volume.
+func validateBlockDeviceNames(client awsclient.Client, amiID string, mappings []machinev1.BlockDeviceMappingSpec) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	images, err := client.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{amiID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing AMI %s: %v", amiID, err)
+		return fmt.Errorf("error describing AMI %s: %v", amiID, err)
+	}
+	if len(images.Images) == 0 {
+		return fmt.Errorf("AMI %s not found", amiID)
+	}
+	rootDeviceName := aws.StringValue(images.Images[0].RootDeviceName)
+
+	deviceNames := map[string]bool{}
+	for _, mapping := range mappings {
+		deviceName := rootDeviceName
+		if mapping.DeviceName != nil {
+			deviceName = aws.StringValue(mapping.DeviceName)
+			if deviceName == rootDeviceName {
+				return fmt.Errorf("block device %s is the root device of AMI %s, omit the device name to configure the root volume", deviceName, amiID)
+			}
+		}
+		if deviceNames[deviceName] {
+			return fmt.Errorf("block device %s is mapped more than once", deviceName)
+		}
+		deviceNames[deviceName] = true
+	}
+	return nil
+}
+
+// validateSubnetFreeIPs checks that the subnet has at least the needed number of free IPv4 addresses,
+// so that launches do not fail with InsufficientFreeAddressesInSubnet.
+func validateSubnetFreeIPs(client awsclient.Client, subnetID string, needed int) error {
+	subnet, err := describeSubnet(client, subnetID)
+	if err != nil {
+		return err
+	}
+	if available := aws.Int64Value(subnet.AvailableIpAddressCount); available < int64(needed) {
+		return fmt.Errorf("subnet %q has %d free IP addresses, %d needed", subnetID, available, needed)
+	}
+	return nil
+}
+
+// validatePublicIPv4Pool checks that the public IPv4 pool, e.g. a BYOIP pool, exists and has addresses left
+// to assign to the instance.
+func validatePublicIPv4Pool(client awsclient.Client, poolID string) error {
+	result, err := client.DescribePublicIpv4Pools(&ec2.DescribePublicIpv4PoolsInput{
+		PoolIds: aws.StringSlice([]string{poolID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing public IPv4 pool %q: %v", poolID, err)
+		return fmt.Errorf("error describing public IPv4 pool %q: %v", poolID, err)
+	}
+	if len(result.PublicIpv4Pools) == 0 {
+		return fmt.Errorf("public IPv4 pool %q not found", poolID)
+	}
+
+	pool := result.PublicIpv4Pools[0]
+	if available := aws.Int64Value(pool.TotalAvailableAddressCount); available < 1 {
+		return fmt.Errorf("public IPv4 pool %q has no available addresses, %d of %d remaining",
+			poolID, available, aws.Int64Value(pool.TotalAddressCount))
+	}
+	return nil
+}
+
+// validateSubnetTGWAttachment checks that the VPC of the subnet has an available attachment to the transit gateway,
+// so that nodes in hub-spoke topologies can reach the rest of the network. An empty transit gateway ID skips the check.
+func validateSubnetTGWAttachment(client awsclient.Client, subnetID, tgwID string) error {
+	if tgwID == "" {
+		return nil
+	}
+
+	subnet, err := describeSubnet(client, subnetID)
+	if err != nil {
+		return err
+	}
+	vpcID := aws.StringValue(subnet.VpcId)
+
+	result, err := client.DescribeTransitGatewayVpcAttachments(&ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("transit-gateway-id"), Values: aws.StringSlice([]string{tgwID})},
+			{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{vpcID})},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Error describing attachments of transit gateway %q: %v", tgwID, err)
+		return fmt.Errorf("error describing attachments of transit gateway %q: %v", tgwID, err)
+	}
+	for _, attachment := range result.TransitGatewayVpcAttachments {
+		if aws.StringValue(attachment.State) == ec2.TransitGatewayAttachmentStateAvailable {
+			return nil
+		}
+	}
+	return fmt.Errorf("VPC %q of subnet %q has no available attachment to transit gateway %q", vpcID, subnetID, tgwID)
+}
+
+// validateSubnetSGSameVPC checks that the security groups are in the VPC of the subnet,
+// as EC2 rejects launching an instance with security groups of another VPC.
+func validateSubnetSGSameVPC(client awsclient.Client, subnetID string, sgIDs []string) error {
+	if len(sgIDs) == 0 {
+		return nil
+	}
+
+	subnet, err := describeSubnet(client, subnetID)
+	if err != nil {
+		return err
+	}
+	vpcID := aws.StringValue(subnet.VpcId)
+
+	result, err := client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: aws.StringSlice(sgIDs),
+	})
+	if err != nil {
+		klog.Errorf("Error describing security groups %v: %v", sgIDs, err)
+		return fmt.Errorf("error describing security groups %v: %v", sgIDs, err)
+	}
+
+	mismatches := []string{}
+	for _, group := range result.SecurityGroups {
+		if groupVPCID := aws.StringValue(group.VpcId); groupVPCID != vpcID {
+			mismatches = append(mismatches, fmt.Sprintf("%s (%s)", aws.StringValue(group.GroupId), groupVPCID))
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("security groups %s are not in VPC %q of subnet %q", strings.Join(mismatches, ", "), vpcID, subnetID)
+	}
+	return nil
+}
+
+// validateZoneOptIn checks that the account opted in to the zone group of the availability zone.
+// Local Zones and Wavelength Zones require an opt-in, launching in them fails otherwise.
+func validateZoneOptIn(client awsclient.Client, az string) error {
+	result, err := client.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{
+		// Zones of groups the account did not opt in to are only listed with AllAvailabilityZones
+		AllAvailabilityZones: aws.Bool(true),
+		ZoneNames:            aws.StringSlice([]string{az}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing availability zone %q: %v", az, err)
+		return fmt.Errorf("error describing availability zone %q: %v", az, err)
+	}
+	if len(result.AvailabilityZones) == 0 {
+		return fmt.Errorf("availability zone %q not found", az)
+	}
+
+	zone := result.AvailabilityZones[0]
+	if aws.StringValue(zone.OptInStatus) == ec2.AvailabilityZoneOptInStatusNotOptedIn {
+		return fmt.Errorf("availability zone %q requires the account to opt in to zone group %q", az, aws.StringValue(zone.GroupName))
+	}
+	return nil
+}
+
+// validateEIPBorderGroup checks that the Elastic IP belongs to the network border group of the availability zone,
+// as an Elastic IP can only be associated with instances in its network border group.
+func validateEIPBorderGroup(client awsclient.Client, allocationID, az string) error {
+	addresses, err := client.DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: aws.StringSlice([]string{allocationID}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing Elastic IP %q: %v", allocationID, err)
+		return fmt.Errorf("error describing Elastic IP %q: %v", allocationID, err)
+	}
+	if len(addresses.Addresses) == 0 {
+		return fmt.Errorf("Elastic IP %q not found", allocationID)
+	}
+	addressBorderGroup := aws.StringValue(addresses.Addresses[0].NetworkBorderGroup)
+
+	zones, err := client.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{
+		ZoneNames: aws.StringSlice([]string{az}),
+	})
+	if err != nil {
+		klog.Errorf("Error describing availability zone %q: %v", az, err)
+		return fmt.Errorf("error describing availability zone %q: %v", az, err)
+	}
+	if len(zones.AvailabilityZones) == 0 {
+		return fmt.Errorf("availability zone %q not found", az)
+	}
+	zoneBorderGroup := aws.StringValue(zones.AvailabilityZones[0].NetworkBorderGroup)
+
+	if addressBorderGroup != zoneBorderGroup {
+		return fmt.Errorf("Elastic IP %q belongs to network border group %q, availability zone %q belongs to network border group %q",
+			allocationID, addressBorderGroup, az, zoneBorderGroup)
+	}
+	return nil
+}
+
+// spotPriceProductDescription is the product the spot prices of Linux instances are reported for.
+const spotPriceProductDescription = "Linux/UNIX"
+
+// validateSpotPrice checks that the maximum spot price is not below the current spot price of the instance type
+// in the availability zone, as spot instances would never launch. Without availability zone the lowest price across
+// the zones of the region is used. An empty maximum price caps the price to the on-demand price and is valid.
+func validateSpotPrice(client awsclient.Client, instanceType, az, maxPrice string) error {
+	if maxPrice == "" {
+		return nil
+	}
+	max, err := strconv.ParseFloat(maxPrice, 64)
+	if err != nil {
+		return fmt.Errorf("invalid spot max price %q: %v", maxPrice, err)
+	}
+
+	input := &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       aws.StringSlice([]string{instanceType}),
+		ProductDescriptions: aws.StringSlice([]string{spotPriceProductDescription}),
+		StartTime:           aws.Time(time.Now()),
+	}
+	if az != "" {
+		input.AvailabilityZone = aws.String(az)
+	}
+	result, err := client.DescribeSpotPriceHistory(input)
+	if err != nil {
+		klog.Errorf("Error describing spot price of instance type %q: %v", instanceType, err)
+		return fmt.Errorf("error describing spot price of instance type %q: %v", instanceType, err)
+	}
+
+	currentPrice := ""
+	current := 0.0
+	for _, spotPrice := range result.SpotPriceHistory {
+		price, err := strconv.ParseFloat(aws.StringValue(spotPrice.SpotPrice), 64)
+		if err != nil {
+			continue
+		}
+		if currentPrice == "" || price < current {
+			currentPrice, current = aws.StringValue(spotPrice.SpotPrice), price
+		}
+	}
+	if currentPrice == "" {
+		klog.Warningf("No current spot price found for instance type %q, unable to validate spot max price %s", instanceType, maxPrice)
+		return nil
+	}
+
+	if max < current {
+		return fmt.Errorf("spot max price %s is below the current spot price %s of instance type %q, spot instances will not launch",
+			maxPrice, currentPrice, instanceType)
+	}
+	return nil
+}
+
+// validateEnclaveOptions checks that the instance type supports AWS Nitro Enclaves when they are requested.
+// Enclaves can only be enabled at launch, so an unsupported instance type is rejected up front
+// with the list of supported instance families.
+func validateEnclaveOptions(enclaveOptions *ec2.EnclaveOptionsRequest, instanceType string) error {
+	if enclaveOptions == nil || !aws.BoolValue(enclaveOptions.Enabled) {
+		return nil
+	}
+
+	family, size := instanceType, ""
+	if i := strings.Index(instanceType, "."); i >= 0 {
+		family, size = instanceType[:i], instanceType[i+1:]
+	}
+
+	familySupported := false
+	for _, supported := range enclaveSupportedInstanceFamilies {
+		if family == supported {
+			familySupported = true
+			break
+		}
+	}
+	sizeSupported := size != ""
+	for _, unsupported := range enclaveUnsupportedInstanceSizes {
+		if size == unsupported {
+			sizeSupported = false
+			break
+		}
+	}
+
+	if !familySupported || !sizeSupported {
+		return fmt.Errorf("instance type %q does not support Nitro Enclaves, supported instance families are %s with a size of xlarge or above",
+			instanceType, strings.Join(enclaveSupportedInstanceFamilies, ", "))
+	}
+	return nil
+}
+
+// validateAcceleratorInstanceType checks that the instance type provides the accelerators requested by the provider spec.
+// When requireGPU is set the instance type must provide GPUs, otherwise any accelerator, GPU or not, is accepted.
+func validateAcceleratorInstanceType(instanceType string, requireGPU bool) error {
+	family := strings.SplitN(instanceType, ".", 2)[0]
+	isGPU := stringSliceContains(gpuInstanceFamilies, family)
+
+	if requireGPU {
+		if !isGPU {
+			return fmt.Errorf("instance type %q does not provide GPUs, GPU instance families are %s",
+				instanceType, strings.Join(gpuInstanceFamilies, ", "))
+		}
+		return nil
+	}
+	if !isGPU && !stringSliceContains(acceleratorInstanceFamilies, family) {
+		return fmt.Errorf("instance type %q does not provide accelerators, accelerated instance families are %s",
+			instanceType, strings.Join(append(append([]string{}, gpuInstanceFamilies...), acceleratorInstanceFamilies...), ", "))
+	}
+	return nil
+}
+
+// validateInstanceTypeOffering checks that the instance type is offered in the location, a region, an availability zone
+// or an availability zone ID depending on the location type.
+func validateInstanceTypeOffering(client awsclient.Client, instanceType, locationType, location string) error {
+	result, err := client.DescribeInstanceTypeOfferings(&ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(locationType),
+		Filters: []*ec2.Filter{
+			{Name: aws.String("instance-type"), Values: aws.StringSlice([]string{instanceType})},
+			{Name: aws.String("location"), Values: aws.StringSlice([]string{location})},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Error describing offerings of instance type %q in %s %q: %v", instanceType, locationType, location, err)
+		return fmt.Errorf("error describing offerings of instance type %q in %s %q: %v", instanceType, locationType, location, err)
+	}
+	if len(result.InstanceTypeOfferings) == 0 {
+		return fmt.Errorf("instance type %q is not offered in %s %q", instanceType, locationType, location)
+	}
+	return nil
+}
+
+// validateAcceleratorAZ checks that the accelerated instance type is offered in the availability zone.
+// Accelerated instance types are often only offered in some of the availability zones of a region.
+func validateAcceleratorAZ(client awsclient.Client, instanceType, az string) error {
+	if err := validateAcceleratorInstanceType(instanceType, false); err != nil {
+		return err
+	}
+	if err := validateInstanceTypeOffering(client, instanceType, ec2.LocationTypeAvailabilityZone, az); err != nil {
+		return fmt.Errorf("accelerated instance type %q cannot be launched in availability zone %q: %w", instanceType, az, err)
+	}
+	return nil
+}
+
+// validateTenancyForInstanceType checks that the instance type can run with the tenancy:
+// burstable previous generation types cannot run on single-tenant hardware, and Mac types
+// can only run on Dedicated Hosts. An empty tenancy is the default, shared tenancy.
+func validateTenancyForInstanceType(instanceType, tenancy string) error {
+	if tenancy == "" {
+		tenancy = string(machinev1.DefaultTenancy)
+	}
+	family := strings.SplitN(instanceType, ".", 2)[0]
+
+	switch machinev1.InstanceTenancy(tenancy) {
+	case machinev1.DefaultTenancy:
+		if stringSliceContains(hostTenancyOnlyInstanceFamilies, family) {
+			return fmt.Errorf("instance type %q can only run with %s tenancy", instanceType, machinev1.HostTenancy)
+		}
+	case machinev1.DedicatedTenancy:
+		if stringSliceContains(hostTenancyOnlyInstanceFamilies, family) {
+			return fmt.Errorf("instance type %q can only run with %s tenancy", instanceType, machinev1.HostTenancy)
+		}
+		if stringSliceContains(singleTenantUnsupportedInstanceFamilies, family) {
+			return fmt.Errorf("instance type %q does not support %s tenancy", instanceType, tenancy)
+		}
+	case machinev1.HostTenancy:
+		if stringSliceContains(singleTenantUnsupportedInstanceFamilies, family) {
+			return fmt.Errorf("instance type %q does not support %s tenancy", instanceType, tenancy)
+		}
+	default:
+		return fmt.Errorf("invalid instance tenancy %q, allowed options are %s, %s, %s",
+			tenancy, machinev1.DefaultTenancy, machinev1.DedicatedTenancy, machinev1.HostTenancy)
+	}
+	return nil
+}
+
+// validateVolumeIOPS checks that the provisioned IOPS are valid for the volume type and size. A zero IOPS value
+// means that no IOPS were requested and is always valid, as the volume then gets the default of its type.
+// The gp3 baseline of 3000 IOPS is available regardless of the volume size, so the ratio to the size only
+// bounds IOPS above the minimum of the type.
+func validateVolumeIOPS(volumeType string, sizeGiB, iops int64) error {
+	if iops == 0 {
+		return nil
+	}
+	if iops < 0 {
+		return fmt.Errorf("invalid IOPS %d, must not be negative", iops)
+	}
+
+	limit, ok := volumeIOPSLimits[volumeType]
+	if !ok {
+		return fmt.Errorf("volume type %q does not support provisioned IOPS, %d requested", volumeType, iops)
+	}
+	if iops < limit.minIOPS || iops > limit.maxIOPS {
+		return fmt.Errorf("volume type %q supports between %d and %d IOPS, %d requested", volumeType, limit.minIOPS, limit.maxIOPS, iops)
+	}
+	if sizeGiB <= 0 {
+		return fmt.Errorf("invalid volume size %d GiB, must be positive", sizeGiB)
+	}
+	if iops > limit.minIOPS && iops > sizeGiB*limit.maxIOPSPerGiB {
+		return fmt.Errorf("volume type %q supports at most %d IOPS per GiB, %d IOPS requested for a %d GiB volume",
+			volumeType, limit.maxIOPSPerGiB, iops, sizeGiB)
+	}
+	return nil
+}
+
+// validateEBSOptimized checks that EBS optimization, when requested, is supported by the instance type.
+// Requesting it on instance types where it is enabled by default is valid but has no effect.
+func validateEBSOptimized(instanceType string, requested bool) error {
+	if !requested {
+		return nil
+	}
+
+	family := strings.SplitN(instanceType, ".", 2)[0]
+	switch {
+	case stringSliceContains(ebsOptimizedUnsupportedInstanceFamilies, family), stringSliceContains(ebsOptimizedUnsupportedInstanceTypes, instanceType):
+		return fmt.Errorf("instance type %q does not support EBS optimization", instanceType)
+	case !stringSliceContains(ebsOptimizedOptionalInstanceFamilies, family):
+		klog.V(4).Infof("EBS optimization is enabled by default on instance type %q", instanceType)
+	}
+	return nil
+}
+
+func stringSliceContains(slice []string, value string) bool {
+	for _, s := range slice {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHostResourceGroup checks that the host resource group exists and is in the region the machine is launched in,
+// e.g. as returned by machineRegion. An empty region skips the region check.
+// An empty ARN is valid and means that no host resource group was requested.
+func validateHostResourceGroup(client awsclient.Client, groupARN, region string) error {
+	if groupARN == "" {
+		return nil
+	}
+
+	parsedARN, err := arn.Parse(groupARN)
+	if err != nil {
+		return fmt.Errorf("invalid host resource group ARN %q: %v", groupARN, err)
+	}
+	if parsedARN.Service != "resource-groups" || !strings.HasPrefix(parsedARN.Resource, "group/") {
+		return fmt.Errorf("invalid host resource group ARN %q: not a resource group ARN", groupARN)
+	}
+	if region != "" && parsedARN.Region != region {
+		return fmt.Errorf("host resource group %q is in region %q, expected region %q", groupARN, parsedARN.Region, region)
+	}
+
+	result, err := client.GetResourceGroup(&resourcegroups.GetGroupInput{
+		Group: aws.String(groupARN),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == resourcegroups.ErrCodeNotFoundException {
+			return fmt.Errorf("host resource group %q not found", groupARN)
+		}
+		klog.Errorf("Error getting host resource group %q: %v", groupARN, err)
+		return fmt.Errorf("error getting host resource group %q: %v", groupARN, err)
+	}
+	if result.Group == nil || result.Group.GroupArn == nil {
+		return fmt.Errorf("host resource group %q not found", groupARN)
+	}
+
+	return nil
+}
+
+// maxPodsForInstanceType returns the number of pods the AWS VPC CNI can give an IP address to on the instance type:
+// each network interface provides its secondary IPv4 addresses, i.e. all its addresses but the primary one.
+// Pods using the host network do not need an address and are not counted.
+func maxPodsForInstanceType(instanceType string) (int, error) {
+	maxInterfaces, ok := maxNetworkInterfacesByInstanceType[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("network interface limit of instance type %q is not known", instanceType)
+	}
+	addressesPerInterface, ok := ipv4AddressesPerInterfaceByInstanceType[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("IPv4 address limit per network interface of instance type %q is not known", instanceType)
+	}
+	return maxInterfaces * (addressesPerInterface - 1), nil
+}
+
+// validateInstanceStore checks that the instance type has at least the requested number of instance store volumes.
+// Instance types missing from both instanceStoreVolumesByInstanceType and maxNetworkInterfacesByInstanceType
+// are not validated and left to EC2 to reject.
+func validateInstanceStore(instanceType string, requestedEphemeral int) error {
+	if requestedEphemeral <= 0 {
+		return nil
+	}
+
+	volumes, ok := instanceStoreVolumesByInstanceType[instanceType]
+	if !ok {
+		if _, ok := maxNetworkInterfacesByInstanceType[instanceType]; !ok {
+			klog.V(4).Infof("Instance store volumes of instance type %q are not known, skipping validation", instanceType)
+			return nil
+		}
+	}
+	if volumes == 0 {
+		return fmt.Errorf("instance type %q has no instance store volumes, %d requested", instanceType, requestedEphemeral)
+	}
+	if requestedEphemeral > volumes {
+		return fmt.Errorf("instance type %q has %d instance store volumes, %d requested", instanceType, volumes, requestedEphemeral)
+	}
+	return nil
+}
+
+// validateENICount checks that the instance type supports the requested number of network interfaces.
+// Instance types missing from maxNetworkInterfacesByInstanceType are not validated and left to EC2 to reject.
+func validateENICount(instanceType string, requested int) error {
+	if requested < 1 {
+		return fmt.Errorf("at least one network interface is required, got %d", requested)
+	}
+
+	maxInterfaces, ok := maxNetworkInterfacesByInstanceType[instanceType]
+	if !ok {
+		klog.V(4).Infof("Network interface limit of instance type %q is not known, skipping validation", instanceType)
+		return nil
+	}
+	if requested > maxInterfaces {
+		return fmt.Errorf("instance type %q supports at most %d network interfaces, %d requested", instanceType, maxInterfaces, requested)
+	}
+	return nil
+}
+
+// validateIPv6Support checks that the subnet has an IPv6 CIDR block associated, which is required
+// to launch instances with IPv6 addresses. It should only be called when IPv6 addressing is requested.
+func validateIPv6Support(client awsclient.Client, subnetID string) error {
+	subnet, err := describeSubnet(client, subnetID)
+	if err != nil {
+		return err
+	}
+
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && aws.StringValue(association.Ipv6CidrBlockState.State) == ec2.SubnetCidrBlockStateCodeAssociated {
+			return nil
+		}
+	}
+	return fmt.Errorf("IPv6 addressing requested but subnet %q has no IPv6 CIDR block associated", subnetID)
+}
+
+// validateAZInCluster checks that the requested availability zone is covered by one of the cluster subnets,
+// so that machines do not land in zones the cluster networking does not reach. When the Infrastructure object
+// is known, only the subnets tagged for its cluster are considered, otherwise all the given subnets are.
+// An empty availability zone is valid and means that no zone was requested.
+func validateAZInCluster(infra *configv1.Infrastructure, az string, subnets []*ec2.Subnet) error {
+	if az == "" {
+		return nil
+	}
+
+	clusterTagKey := ""
+	if infra != nil && infra.Status.InfrastructureName != "" {
+		clusterTagKey = clusterFilterKey(infra.Status.InfrastructureName)
+	}
+
+	zones := map[string]struct{}{}
+	for _, subnet := range subnets {
+		if clusterTagKey != "" && !hasTagKey(subnet.Tags, clusterTagKey) {
+			continue
+		}
+		zones[aws.StringValue(subnet.AvailabilityZone)] = struct{}{}
+	}
+	if _, ok := zones[az]; ok {
+		return nil
+	}
+
+	validZones := make([]string, 0, len(zones))
+	for zone := range zones {
+		validZones = append(validZones, zone)
+	}
+	sort.Strings(validZones)
+	return fmt.Errorf("availability zone %q has no cluster subnet, valid availability zones are: %s", az, strings.Join(validZones, ", "))
+}
+
+func hasTagKey(tags []*ec2.Tag, key string) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSpotCapacityReservation checks that spot market options are not combined with a targeted capacity reservation.
+// Spot instances cannot be launched into a capacity reservation and EC2 rejects the combination at launch.
+func validateSpotCapacityReservation(spotMarketOptions *machinev1.SpotMarketOptions, capacityReservation *ec2.CapacityReservationSpecification) error {
+	if spotMarketOptions == nil || capacityReservation == nil || capacityReservation.CapacityReservationTarget == nil {
+		return nil
+	}
+	target := capacityReservation.CapacityReservationTarget
+	if target.CapacityReservationId == nil && target.CapacityReservationResourceGroupArn == nil {
+		return nil
+	}
+	return mapierrors.InvalidMachineConfiguration("spot market options cannot be combined with a targeted capacity reservation")
+}
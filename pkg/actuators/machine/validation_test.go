@@ -0,0 +1,1672 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+)
+
+func TestValidateLaunchTemplate(t *testing.T) {
+	template := &ec2.LaunchTemplate{
+		LaunchTemplateId:     aws.String("lt-0123456789"),
+		LaunchTemplateName:   aws.String("workers"),
+		DefaultVersionNumber: aws.Int64(2),
+		LatestVersionNumber:  aws.Int64(5),
+	}
+	describeVersions := func(version string) *ec2.DescribeLaunchTemplateVersionsInput {
+		return &ec2.DescribeLaunchTemplateVersionsInput{
+			LaunchTemplateId: aws.String("lt-0123456789"),
+			Versions:         aws.StringSlice([]string{version}),
+		}
+	}
+	foundVersion := &ec2.DescribeLaunchTemplateVersionsOutput{
+		LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{{VersionNumber: aws.Int64(1)}},
+	}
+
+	testCases := []struct {
+		name          string
+		templateID    string
+		templateName  string
+		version       string
+		expectations  func(*mockaws.MockClientMockRecorder)
+		expectedError string
+	}{
+		{
+			name:       "Template and version exist",
+			templateID: "lt-0123456789",
+			version:    "3",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(&ec2.DescribeLaunchTemplatesInput{LaunchTemplateIds: aws.StringSlice([]string{"lt-0123456789"})}).
+					Return(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: []*ec2.LaunchTemplate{template}}, nil)
+				m.DescribeLaunchTemplateVersions(describeVersions("3")).Return(foundVersion, nil)
+			},
+		},
+		{
+			name:         "Latest alias is resolved",
+			templateName: "workers",
+			version:      launchTemplateVersionLatest,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(&ec2.DescribeLaunchTemplatesInput{LaunchTemplateNames: aws.StringSlice([]string{"workers"})}).
+					Return(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: []*ec2.LaunchTemplate{template}}, nil)
+				m.DescribeLaunchTemplateVersions(describeVersions("5")).Return(foundVersion, nil)
+			},
+		},
+		{
+			name:         "Default alias is resolved",
+			templateName: "workers",
+			version:      launchTemplateVersionDefault,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(gomock.Any()).Return(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: []*ec2.LaunchTemplate{template}}, nil)
+				m.DescribeLaunchTemplateVersions(describeVersions("2")).Return(foundVersion, nil)
+			},
+		},
+		{
+			name:         "Empty version defaults to the default version",
+			templateName: "workers",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(gomock.Any()).Return(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: []*ec2.LaunchTemplate{template}}, nil)
+				m.DescribeLaunchTemplateVersions(describeVersions("2")).Return(foundVersion, nil)
+			},
+		},
+		{
+			name:         "Missing template",
+			templateName: "missing",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(gomock.Any()).Return(nil, awserr.New("InvalidLaunchTemplateName.NotFoundException", "not found", nil))
+			},
+			expectedError: "launch template \"missing\" not found",
+		},
+		{
+			name:       "Missing template with empty result",
+			templateID: "lt-missing",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(gomock.Any()).Return(&ec2.DescribeLaunchTemplatesOutput{}, nil)
+			},
+			expectedError: "launch template \"lt-missing\" not found",
+		},
+		{
+			name:       "Missing version",
+			templateID: "lt-0123456789",
+			version:    "7",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(gomock.Any()).Return(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: []*ec2.LaunchTemplate{template}}, nil)
+				m.DescribeLaunchTemplateVersions(describeVersions("7")).Return(nil, awserr.New("InvalidLaunchTemplateId.VersionNotFound", "not found", nil))
+			},
+			expectedError: "version 7 of launch template \"lt-0123456789\" not found",
+		},
+		{
+			name:       "Invalid version",
+			templateID: "lt-0123456789",
+			version:    "$Newest",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeLaunchTemplates(gomock.Any()).Return(&ec2.DescribeLaunchTemplatesOutput{LaunchTemplates: []*ec2.LaunchTemplate{template}}, nil)
+			},
+			expectedError: "invalid launch template version \"$Newest\": must be a number, $Latest or $Default",
+		},
+		{
+			name:          "No template reference",
+			expectations:  func(m *mockaws.MockClientMockRecorder) {},
+			expectedError: "launch template ID or name need to be specified",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := validateLaunchTemplate(mockAWSClient, tc.templateID, tc.templateName, tc.version)
+			if tc.expectedError == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tc.expectedError != "" && (err == nil || err.Error() != tc.expectedError) {
+				t.Errorf("Expected error %q, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeAvailabilityZone(t *testing.T) {
+	testCases := []struct {
+		az          string
+		expected    string
+		expectError bool
+	}{
+		{az: "", expected: ""},
+		{az: "us-east-1a", expected: "us-east-1a"},
+		{az: "US-East-1A", expected: "us-east-1a"},
+		{az: " eu-west-2c ", expected: "eu-west-2c"},
+		{az: "us-gov-west-1b", expected: "us-gov-west-1b"},
+		{az: "us-iso-east-1a", expected: "us-iso-east-1a"},
+		{az: "cn-northwest-1a", expected: "cn-northwest-1a"},
+		{az: "us-east-1-bos-1a", expected: "us-east-1-bos-1a"},
+		{az: "US-WEST-2-LAX-1B", expected: "us-west-2-lax-1b"},
+		{az: "us-east-1-wl1-bos-wlz-1", expected: "us-east-1-wl1-bos-wlz-1"},
+		{az: "us-east-1", expectError: true},
+		{az: "us-east-1ab", expectError: true},
+		{az: "useast1a", expectError: true},
+		{az: "us-east-1-bos", expectError: true},
+		{az: "us_east_1a", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.az, func(t *testing.T) {
+			normalized, err := normalizeAvailabilityZone(tc.az)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if normalized != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, normalized)
+			}
+		})
+	}
+}
+
+func TestRegionFromAZ(t *testing.T) {
+	testCases := []struct {
+		az          string
+		expected    string
+		expectError bool
+	}{
+		{az: "us-east-1a", expected: "us-east-1"},
+		{az: "EU-WEST-2C", expected: "eu-west-2"},
+		{az: "ap-southeast-2b", expected: "ap-southeast-2"},
+		{az: "us-gov-west-1b", expected: "us-gov-west-1"},
+		{az: "us-isob-east-1a", expected: "us-isob-east-1"},
+		{az: "cn-northwest-1a", expected: "cn-northwest-1"},
+		{az: "us-east-1-bos-1a", expected: "us-east-1"},
+		{az: "us-west-2-lax-1b", expected: "us-west-2"},
+		{az: "us-east-1-wl1-bos-wlz-1", expected: "us-east-1"},
+		{az: "", expectError: true},
+		{az: "us-east-1", expectError: true},
+		{az: "us-east-1ab", expectError: true},
+		{az: "us-east-1-bos", expectError: true},
+		{az: "useast1a", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.az, func(t *testing.T) {
+			region, err := regionFromAZ(tc.az)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if region != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, region)
+			}
+		})
+	}
+}
+
+func TestValidateUserDataSize(t *testing.T) {
+	// Every 3 bytes of input are encoded to 4 bytes of base64,
+	// so 12288 bytes of user data encode to exactly 16384 bytes.
+	testCases := []struct {
+		name        string
+		size        int
+		expectError bool
+	}{
+		{
+			name: "Empty user data",
+			size: 0,
+		},
+		{
+			name: "Just under the limit",
+			size: 12285,
+		},
+		{
+			name: "Exactly at the limit",
+			size: 12288,
+		},
+		{
+			name:        "Just over the limit",
+			size:        12289,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUserDataSize(make([]byte, tc.size))
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateKeyPair(t *testing.T) {
+	describeKeyPairs := func(name string) *ec2.DescribeKeyPairsInput {
+		return &ec2.DescribeKeyPairsInput{KeyNames: aws.StringSlice([]string{name})}
+	}
+
+	testCases := []struct {
+		name          string
+		keyName       string
+		expectations  func(*mockaws.MockClientMockRecorder)
+		expectedError string
+	}{
+		{
+			name:    "Key pair exists",
+			keyName: "ssh-key",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeKeyPairs(describeKeyPairs("ssh-key")).
+					Return(&ec2.DescribeKeyPairsOutput{KeyPairs: []*ec2.KeyPairInfo{{KeyName: aws.String("ssh-key")}}}, nil)
+			},
+		},
+		{
+			name:    "Key pair does not exist",
+			keyName: "deleted-key",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeKeyPairs(describeKeyPairs("deleted-key")).
+					Return(nil, awserr.New("InvalidKeyPair.NotFound", "The key pair 'deleted-key' does not exist", nil))
+			},
+			expectedError: "key pair \"deleted-key\" not found",
+		},
+		{
+			name:    "Key pair missing from an empty result",
+			keyName: "deleted-key",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeKeyPairs(describeKeyPairs("deleted-key")).Return(&ec2.DescribeKeyPairsOutput{}, nil)
+			},
+			expectedError: "key pair \"deleted-key\" not found",
+		},
+		{
+			name:         "No key pair requested",
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := validateKeyPair(mockAWSClient, tc.keyName)
+			if tc.expectedError == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tc.expectedError != "" && (err == nil || err.Error() != tc.expectedError) {
+				t.Errorf("Expected error %q, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateEnclaveOptions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		enclaveOptions *ec2.EnclaveOptionsRequest
+		instanceType   string
+		expectError    bool
+	}{
+		{
+			name:           "Supported instance type",
+			enclaveOptions: &ec2.EnclaveOptionsRequest{Enabled: aws.Bool(true)},
+			instanceType:   "m5.xlarge",
+		},
+		{
+			name:           "Supported Graviton instance type",
+			enclaveOptions: &ec2.EnclaveOptionsRequest{Enabled: aws.Bool(true)},
+			instanceType:   "c6g.4xlarge",
+		},
+		{
+			name:           "Unsupported instance family",
+			enclaveOptions: &ec2.EnclaveOptionsRequest{Enabled: aws.Bool(true)},
+			instanceType:   "t3.xlarge",
+			expectError:    true,
+		},
+		{
+			name:           "Unsupported instance size",
+			enclaveOptions: &ec2.EnclaveOptionsRequest{Enabled: aws.Bool(true)},
+			instanceType:   "m5.large",
+			expectError:    true,
+		},
+		{
+			name:           "Bare metal",
+			enclaveOptions: &ec2.EnclaveOptionsRequest{Enabled: aws.Bool(true)},
+			instanceType:   "m5.metal",
+			expectError:    true,
+		},
+		{
+			name:           "Enclaves disabled",
+			enclaveOptions: &ec2.EnclaveOptionsRequest{Enabled: aws.Bool(false)},
+			instanceType:   "t3.micro",
+		},
+		{
+			name:         "Enclaves not requested",
+			instanceType: "t3.micro",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateEnclaveOptions(tc.enclaveOptions, tc.instanceType)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateHostResourceGroup(t *testing.T) {
+	groupARN := "arn:aws:resource-groups:us-east-1:123456789012:group/licensed-hosts"
+	getGroup := func(groupARN string) *resourcegroups.GetGroupInput {
+		return &resourcegroups.GetGroupInput{Group: aws.String(groupARN)}
+	}
+
+	testCases := []struct {
+		name          string
+		groupARN      string
+		region        string
+		expectations  func(*mockaws.MockClientMockRecorder)
+		expectedError string
+	}{
+		{
+			name:     "Group exists",
+			groupARN: groupARN,
+			region:   "us-east-1",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetResourceGroup(getGroup(groupARN)).
+					Return(&resourcegroups.GetGroupOutput{Group: &resourcegroups.Group{GroupArn: aws.String(groupARN)}}, nil)
+			},
+		},
+		{
+			name:     "Group does not exist",
+			groupARN: groupARN,
+			region:   "us-east-1",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetResourceGroup(getGroup(groupARN)).
+					Return(nil, awserr.New(resourcegroups.ErrCodeNotFoundException, "Cannot find group licensed-hosts.", nil))
+			},
+			expectedError: "host resource group \"" + groupARN + "\" not found",
+		},
+		{
+			name:          "Group in another region",
+			groupARN:      "arn:aws:resource-groups:eu-west-1:123456789012:group/licensed-hosts",
+			region:        "us-east-1",
+			expectations:  func(m *mockaws.MockClientMockRecorder) {},
+			expectedError: "host resource group \"arn:aws:resource-groups:eu-west-1:123456789012:group/licensed-hosts\" is in region \"eu-west-1\", expected region \"us-east-1\"",
+		},
+		{
+			name:     "No region to check against",
+			groupARN: "arn:aws:resource-groups:eu-west-1:123456789012:group/licensed-hosts",
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.GetResourceGroup(getGroup("arn:aws:resource-groups:eu-west-1:123456789012:group/licensed-hosts")).
+					Return(&resourcegroups.GetGroupOutput{Group: &resourcegroups.Group{GroupArn: aws.String("arn:aws:resource-groups:eu-west-1:123456789012:group/licensed-hosts")}}, nil)
+			},
+		},
+		{
+			name:          "Not a resource group ARN",
+			groupARN:      "arn:aws:ec2:us-east-1:123456789012:dedicated-host/h-0123456789",
+			region:        "us-east-1",
+			expectations:  func(m *mockaws.MockClientMockRecorder) {},
+			expectedError: "invalid host resource group ARN \"arn:aws:ec2:us-east-1:123456789012:dedicated-host/h-0123456789\": not a resource group ARN",
+		},
+		{
+			name:         "No host resource group requested",
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := validateHostResourceGroup(mockAWSClient, tc.groupARN, tc.region)
+			if tc.expectedError == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tc.expectedError != "" && (err == nil || err.Error() != tc.expectedError) {
+				t.Errorf("Expected error %q, got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateENICount(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		requested    int
+		expectError  bool
+	}{
+		{instanceType: "m4.large", requested: 2},
+		{instanceType: "m4.large", requested: 3, expectError: true},
+		{instanceType: "m5.large", requested: 3},
+		{instanceType: "m5.xlarge", requested: 5, expectError: true},
+		{instanceType: "m5.24xlarge", requested: 15},
+		{instanceType: "m5.24xlarge", requested: 16, expectError: true},
+		{instanceType: "m5.large", requested: 0, expectError: true},
+		{instanceType: "unknown.large", requested: 32},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s with %d interfaces", tc.instanceType, tc.requested), func(t *testing.T) {
+			err := validateENICount(tc.instanceType, tc.requested)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPv6Support(t *testing.T) {
+	cidrBlock := func(state string) *ec2.SubnetIpv6CidrBlockAssociation {
+		return &ec2.SubnetIpv6CidrBlockAssociation{
+			Ipv6CidrBlock:      aws.String("2600:1f18:abcd:1200::/64"),
+			Ipv6CidrBlockState: &ec2.SubnetCidrBlockState{State: aws.String(state)},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		subnet      *ec2.Subnet
+		expectError bool
+	}{
+		{
+			name: "IPv6 enabled subnet",
+			subnet: &ec2.Subnet{
+				SubnetId:                    aws.String("subnet-1"),
+				Ipv6CidrBlockAssociationSet: []*ec2.SubnetIpv6CidrBlockAssociation{cidrBlock(ec2.SubnetCidrBlockStateCodeAssociated)},
+			},
+		},
+		{
+			name:        "IPv4 only subnet",
+			subnet:      &ec2.Subnet{SubnetId: aws.String("subnet-1")},
+			expectError: true,
+		},
+		{
+			name: "IPv6 CIDR block being disassociated",
+			subnet: &ec2.Subnet{
+				SubnetId:                    aws.String("subnet-1"),
+				Ipv6CidrBlockAssociationSet: []*ec2.SubnetIpv6CidrBlockAssociation{cidrBlock(ec2.SubnetCidrBlockStateCodeDisassociating)},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-1"})}).
+				Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{tc.subnet}}, nil)
+
+			err := validateIPv6Support(mockAWSClient, "subnet-1")
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateAcceleratorInstanceType(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		requireGPU   bool
+		expectError  bool
+	}{
+		{instanceType: "p3.2xlarge", requireGPU: true},
+		{instanceType: "g4dn.xlarge", requireGPU: true},
+		{instanceType: "g5g.xlarge", requireGPU: true},
+		{instanceType: "inf1.xlarge", requireGPU: true, expectError: true},
+		{instanceType: "m5.large", requireGPU: true, expectError: true},
+		{instanceType: "inf2.xlarge", requireGPU: false},
+		{instanceType: "trn1.2xlarge", requireGPU: false},
+		{instanceType: "p4d.24xlarge", requireGPU: false},
+		{instanceType: "m5.large", requireGPU: false, expectError: true},
+		{instanceType: "g", requireGPU: true, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s requireGPU=%t", tc.instanceType, tc.requireGPU), func(t *testing.T) {
+			err := validateAcceleratorInstanceType(tc.instanceType, tc.requireGPU)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateTenancyForInstanceType(t *testing.T) {
+	testCases := []struct {
+		instanceType  string
+		tenancy       string
+		expectedError string
+	}{
+		{instanceType: "m5.large", tenancy: ""},
+		{instanceType: "m5.large", tenancy: "default"},
+		{instanceType: "m5.large", tenancy: "dedicated"},
+		{instanceType: "m5.metal", tenancy: "host"},
+		{instanceType: "t3.large", tenancy: "dedicated"},
+		{instanceType: "t2.micro", tenancy: "default"},
+		{instanceType: "t2.micro", tenancy: "dedicated", expectedError: "instance type \"t2.micro\" does not support dedicated tenancy"},
+		{instanceType: "t1.micro", tenancy: "host", expectedError: "instance type \"t1.micro\" does not support host tenancy"},
+		{instanceType: "mac1.metal", tenancy: "host"},
+		{instanceType: "mac2.metal", tenancy: "", expectedError: "instance type \"mac2.metal\" can only run with host tenancy"},
+		{instanceType: "mac1.metal", tenancy: "dedicated", expectedError: "instance type \"mac1.metal\" can only run with host tenancy"},
+		{instanceType: "m5.large", tenancy: "shared", expectedError: "invalid instance tenancy \"shared\", allowed options are default, dedicated, host"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s tenancy=%s", tc.instanceType, tc.tenancy), func(t *testing.T) {
+			err := validateTenancyForInstanceType(tc.instanceType, tc.tenancy)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateEBSOptimized(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		requested    bool
+		expectError  bool
+	}{
+		{instanceType: "m3.xlarge", requested: true},
+		{instanceType: "m5.large", requested: true},
+		{instanceType: "m6i.xlarge", requested: true},
+		{instanceType: "t2.micro", requested: true, expectError: true},
+		{instanceType: "m3.medium", requested: true, expectError: true},
+		{instanceType: "t2.micro", requested: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s requested=%t", tc.instanceType, tc.requested), func(t *testing.T) {
+			err := validateEBSOptimized(tc.instanceType, tc.requested)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateVolumeIOPS(t *testing.T) {
+	testCases := []struct {
+		name        string
+		volumeType  string
+		sizeGiB     int64
+		iops        int64
+		expectError bool
+	}{
+		{name: "No IOPS requested", volumeType: ec2.VolumeTypeGp2, sizeGiB: 120},
+		{name: "Negative IOPS", volumeType: ec2.VolumeTypeGp3, sizeGiB: 120, iops: -1, expectError: true},
+		{name: "gp2 does not support provisioned IOPS", volumeType: ec2.VolumeTypeGp2, sizeGiB: 120, iops: 3000, expectError: true},
+		{name: "st1 does not support provisioned IOPS", volumeType: ec2.VolumeTypeSt1, sizeGiB: 500, iops: 500, expectError: true},
+		{name: "gp3 baseline on a small volume", volumeType: ec2.VolumeTypeGp3, sizeGiB: 1, iops: 3000},
+		{name: "gp3 below minimum", volumeType: ec2.VolumeTypeGp3, sizeGiB: 120, iops: 2000, expectError: true},
+		{name: "gp3 at ratio limit", volumeType: ec2.VolumeTypeGp3, sizeGiB: 10, iops: 5000},
+		{name: "gp3 above ratio limit", volumeType: ec2.VolumeTypeGp3, sizeGiB: 10, iops: 5001, expectError: true},
+		{name: "gp3 at maximum", volumeType: ec2.VolumeTypeGp3, sizeGiB: 120, iops: 16000},
+		{name: "gp3 above maximum", volumeType: ec2.VolumeTypeGp3, sizeGiB: 1000, iops: 16001, expectError: true},
+		{name: "io1 within limits", volumeType: ec2.VolumeTypeIo1, sizeGiB: 100, iops: 5000},
+		{name: "io1 below minimum", volumeType: ec2.VolumeTypeIo1, sizeGiB: 100, iops: 50, expectError: true},
+		{name: "io1 above ratio limit", volumeType: ec2.VolumeTypeIo1, sizeGiB: 100, iops: 5001, expectError: true},
+		{name: "io1 above maximum", volumeType: ec2.VolumeTypeIo1, sizeGiB: 2000, iops: 64001, expectError: true},
+		{name: "io2 at ratio limit", volumeType: ec2.VolumeTypeIo2, sizeGiB: 100, iops: 50000},
+		{name: "io2 above ratio limit", volumeType: ec2.VolumeTypeIo2, sizeGiB: 100, iops: 50001, expectError: true},
+		{name: "io2 above maximum", volumeType: ec2.VolumeTypeIo2, sizeGiB: 1000, iops: 64001, expectError: true},
+		{name: "io2 with invalid size", volumeType: ec2.VolumeTypeIo2, iops: 1000, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateVolumeIOPS(tc.volumeType, tc.sizeGiB, tc.iops)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateAZInCluster(t *testing.T) {
+	subnet := func(az string, tags ...*ec2.Tag) *ec2.Subnet {
+		return &ec2.Subnet{AvailabilityZone: aws.String(az), Tags: tags}
+	}
+	clusterTag := &ec2.Tag{Key: aws.String("kubernetes.io/cluster/cluster-id"), Value: aws.String("shared")}
+	otherClusterTag := &ec2.Tag{Key: aws.String("kubernetes.io/cluster/other"), Value: aws.String("owned")}
+	infra := &configv1.Infrastructure{Status: configv1.InfrastructureStatus{InfrastructureName: "cluster-id"}}
+	subnets := []*ec2.Subnet{
+		subnet("us-east-1a", clusterTag),
+		subnet("us-east-1b", clusterTag),
+		subnet("us-east-1c", otherClusterTag),
+	}
+
+	testCases := []struct {
+		name          string
+		infra         *configv1.Infrastructure
+		az            string
+		expectedError string
+	}{
+		{
+			name:  "Availability zone in cluster",
+			infra: infra,
+			az:    "us-east-1b",
+		},
+		{
+			name:          "Availability zone of another cluster",
+			infra:         infra,
+			az:            "us-east-1c",
+			expectedError: `availability zone "us-east-1c" has no cluster subnet, valid availability zones are: us-east-1a, us-east-1b`,
+		},
+		{
+			name:          "Availability zone without subnet",
+			infra:         infra,
+			az:            "us-east-1d",
+			expectedError: `availability zone "us-east-1d" has no cluster subnet, valid availability zones are: us-east-1a, us-east-1b`,
+		},
+		{
+			name: "Without infrastructure all subnets are considered",
+			az:   "us-east-1c",
+		},
+		{
+			name:  "No availability zone requested",
+			infra: infra,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAZInCluster(tc.infra, tc.az, subnets)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpotCapacityReservation(t *testing.T) {
+	targeted := &ec2.CapacityReservationSpecification{
+		CapacityReservationTarget: &ec2.CapacityReservationTarget{CapacityReservationId: aws.String("cr-0123456789")},
+	}
+
+	testCases := []struct {
+		name                string
+		spotMarketOptions   *machinev1.SpotMarketOptions
+		capacityReservation *ec2.CapacityReservationSpecification
+		expectError         bool
+	}{
+		{
+			name:                "Spot with a targeted capacity reservation",
+			spotMarketOptions:   &machinev1.SpotMarketOptions{},
+			capacityReservation: targeted,
+			expectError:         true,
+		},
+		{
+			name:              "Spot with a capacity reservation resource group",
+			spotMarketOptions: &machinev1.SpotMarketOptions{MaxPrice: aws.String("0.5")},
+			capacityReservation: &ec2.CapacityReservationSpecification{
+				CapacityReservationTarget: &ec2.CapacityReservationTarget{
+					CapacityReservationResourceGroupArn: aws.String("arn:aws:resource-groups:us-east-1:123456789012:group/reservations"),
+				},
+			},
+			expectError: true,
+		},
+		{
+			name:              "Spot with an open capacity reservation preference",
+			spotMarketOptions: &machinev1.SpotMarketOptions{},
+			capacityReservation: &ec2.CapacityReservationSpecification{
+				CapacityReservationPreference: aws.String(ec2.CapacityReservationPreferenceOpen),
+			},
+		},
+		{
+			name:              "Spot alone",
+			spotMarketOptions: &machinev1.SpotMarketOptions{},
+		},
+		{
+			name:                "Capacity reservation alone",
+			capacityReservation: targeted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSpotCapacityReservation(tc.spotMarketOptions, tc.capacityReservation)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			var machineErr *mapierrors.MachineError
+			if err != nil && (!errors.As(err, &machineErr) || machineErr.Reason != machinev1.InvalidConfigurationMachineError) {
+				t.Errorf("Expected invalid configuration error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePartitionNumber(t *testing.T) {
+	placementGroup := func(strategy string, partitionCount int64) *ec2.DescribePlacementGroupsOutput {
+		return &ec2.DescribePlacementGroupsOutput{
+			PlacementGroups: []*ec2.PlacementGroup{{
+				GroupName:      aws.String("workers"),
+				Strategy:       aws.String(strategy),
+				PartitionCount: aws.Int64(partitionCount),
+			}},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		partitionNumber int64
+		output          *ec2.DescribePlacementGroupsOutput
+		outputErr       error
+		expectedError   string
+	}{
+		{
+			name:            "Partition within the group",
+			partitionNumber: 3,
+			output:          placementGroup(ec2.PlacementStrategyPartition, 3),
+		},
+		{
+			name:            "Partition beyond the partition count",
+			partitionNumber: 4,
+			output:          placementGroup(ec2.PlacementStrategyPartition, 3),
+			expectedError:   `partition number 4 is out of range for placement group "workers", valid partitions are 1 to 3`,
+		},
+		{
+			name:            "Partition zero",
+			partitionNumber: 0,
+			output:          placementGroup(ec2.PlacementStrategyPartition, 3),
+			expectedError:   `partition number 0 is out of range for placement group "workers", valid partitions are 1 to 3`,
+		},
+		{
+			name:            "Cluster placement group",
+			partitionNumber: 1,
+			output:          placementGroup(ec2.PlacementStrategyCluster, 0),
+			expectedError:   `placement group "workers" has strategy "cluster", a partition number requires the "partition" strategy`,
+		},
+		{
+			name:            "Placement group not found",
+			partitionNumber: 1,
+			outputErr:       awserr.New("InvalidPlacementGroup.Unknown", "The Placement Group 'workers' is unknown.", nil),
+			expectedError:   `placement group "workers" not found`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribePlacementGroups(&ec2.DescribePlacementGroupsInput{
+				GroupNames: aws.StringSlice([]string{"workers"}),
+			}).Return(tc.output, tc.outputErr)
+
+			err := validatePartitionNumber(mockAWSClient, "workers", tc.partitionNumber)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpotPrice(t *testing.T) {
+	spotPrices := func(prices ...string) *ec2.DescribeSpotPriceHistoryOutput {
+		output := &ec2.DescribeSpotPriceHistoryOutput{}
+		for _, price := range prices {
+			output.SpotPriceHistory = append(output.SpotPriceHistory, &ec2.SpotPrice{
+				InstanceType: aws.String("m5.large"),
+				SpotPrice:    aws.String(price),
+			})
+		}
+		return output
+	}
+
+	testCases := []struct {
+		name          string
+		maxPrice      string
+		output        *ec2.DescribeSpotPriceHistoryOutput
+		describeErr   error
+		expectedError string
+	}{
+		{
+			name: "Empty max price",
+		},
+		{
+			name:     "Max price above the current price",
+			maxPrice: "0.05",
+			output:   spotPrices("0.0412"),
+		},
+		{
+			name:     "Max price equal to the current price",
+			maxPrice: "0.0412",
+			output:   spotPrices("0.0412"),
+		},
+		{
+			name:          "Max price below the current price",
+			maxPrice:      "0.03",
+			output:        spotPrices("0.0412"),
+			expectedError: "spot max price 0.03 is below the current spot price 0.0412 of instance type \"m5.large\", spot instances will not launch",
+		},
+		{
+			name:     "Lowest price across zones",
+			maxPrice: "0.035",
+			output:   spotPrices("0.0412", "0.0345"),
+		},
+		{
+			name:     "No current price",
+			maxPrice: "0.03",
+			output:   spotPrices(),
+		},
+		{
+			name:          "Invalid max price",
+			maxPrice:      "cheap",
+			expectedError: "invalid spot max price \"cheap\": strconv.ParseFloat: parsing \"cheap\": invalid syntax",
+		},
+		{
+			name:          "API error",
+			maxPrice:      "0.03",
+			describeErr:   fmt.Errorf("error"),
+			expectedError: "error describing spot price of instance type \"m5.large\": error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.output != nil || tc.describeErr != nil {
+				mockAWSClient.EXPECT().DescribeSpotPriceHistory(gomock.Any()).DoAndReturn(func(input *ec2.DescribeSpotPriceHistoryInput) (*ec2.DescribeSpotPriceHistoryOutput, error) {
+					if aws.StringValue(input.AvailabilityZone) != "us-east-1a" || aws.StringValueSlice(input.InstanceTypes)[0] != "m5.large" {
+						t.Errorf("Unexpected spot price history input: %v", input)
+					}
+					return tc.output, tc.describeErr
+				})
+			}
+
+			err := validateSpotPrice(mockAWSClient, "m5.large", "us-east-1a", tc.maxPrice)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestMaxPodsForInstanceType(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		expected     int
+		expectError  bool
+	}{
+		{instanceType: "t3.micro", expected: 2},
+		{instanceType: "m5.large", expected: 27},
+		{instanceType: "c5.4xlarge", expected: 232},
+		{instanceType: "m6i.32xlarge", expected: 735},
+		{instanceType: "x2gd.metal", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			maxPods, err := maxPodsForInstanceType(tc.instanceType)
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectError, err)
+			}
+			if maxPods != tc.expected {
+				t.Errorf("Expected %d pods, got %d", tc.expected, maxPods)
+			}
+		})
+	}
+}
+
+func TestValidateInstanceStore(t *testing.T) {
+	testCases := []struct {
+		instanceType  string
+		requested     int
+		expectedError string
+	}{
+		{instanceType: "m5d.large", requested: 1},
+		{instanceType: "i3.8xlarge", requested: 4},
+		{instanceType: "m5.large", requested: 0},
+		{instanceType: "m5d.large", requested: 2, expectedError: "instance type \"m5d.large\" has 1 instance store volumes, 2 requested"},
+		{instanceType: "m5.large", requested: 1, expectedError: "instance type \"m5.large\" has no instance store volumes, 1 requested"},
+		{instanceType: "x2gd.metal", requested: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s requested=%d", tc.instanceType, tc.requested), func(t *testing.T) {
+			err := validateInstanceStore(tc.instanceType, tc.requested)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateAMIArchitecture(t *testing.T) {
+	image := func(architecture string) *ec2.DescribeImagesOutput {
+		return &ec2.DescribeImagesOutput{Images: []*ec2.Image{{ImageId: aws.String("ami-1"), Architecture: aws.String(architecture)}}}
+	}
+	instanceType := func(architectures ...string) *ec2.DescribeInstanceTypesOutput {
+		return &ec2.DescribeInstanceTypesOutput{InstanceTypes: []*ec2.InstanceTypeInfo{{
+			ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice(architectures)},
+		}}}
+	}
+
+	testCases := []struct {
+		name          string
+		instanceType  string
+		image         *ec2.DescribeImagesOutput
+		instanceTypes *ec2.DescribeInstanceTypesOutput
+		expectedError string
+	}{
+		{
+			name:          "x86_64 AMI on x86_64 instance type",
+			instanceType:  "m5.large",
+			image:         image(ec2.ArchitectureValuesX8664),
+			instanceTypes: instanceType(ec2.ArchitectureTypeI386, ec2.ArchitectureTypeX8664),
+		},
+		{
+			name:          "arm64 AMI on Graviton instance type",
+			instanceType:  "m6g.large",
+			image:         image(ec2.ArchitectureValuesArm64),
+			instanceTypes: instanceType(ec2.ArchitectureTypeArm64),
+		},
+		{
+			name:          "x86_64 AMI on Graviton instance type",
+			instanceType:  "m6g.large",
+			image:         image(ec2.ArchitectureValuesX8664),
+			instanceTypes: instanceType(ec2.ArchitectureTypeArm64),
+			expectedError: "AMI ami-1 has architecture x86_64, which instance type \"m6g.large\" does not support, supported architectures are arm64",
+		},
+		{
+			name:          "arm64 AMI on x86_64 instance type",
+			instanceType:  "m5.large",
+			image:         image(ec2.ArchitectureValuesArm64),
+			instanceTypes: instanceType(ec2.ArchitectureTypeI386, ec2.ArchitectureTypeX8664),
+			expectedError: "AMI ami-1 has architecture arm64, which instance type \"m5.large\" does not support, supported architectures are i386, x86_64",
+		},
+		{
+			name:          "AMI not found",
+			instanceType:  "m5.large",
+			image:         &ec2.DescribeImagesOutput{},
+			expectedError: "AMI ami-1 not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-1"})}).Return(tc.image, nil)
+			if tc.instanceTypes != nil {
+				mockAWSClient.EXPECT().DescribeInstanceTypes(&ec2.DescribeInstanceTypesInput{
+					InstanceTypes: aws.StringSlice([]string{tc.instanceType}),
+				}).Return(tc.instanceTypes, nil)
+			}
+
+			err := validateAMIArchitecture(mockAWSClient, "ami-1", tc.instanceType)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateAMIVirtualization(t *testing.T) {
+	image := func(virtualizationType string) *ec2.DescribeImagesOutput {
+		return &ec2.DescribeImagesOutput{Images: []*ec2.Image{{ImageId: aws.String("ami-1"), VirtualizationType: aws.String(virtualizationType)}}}
+	}
+
+	testCases := []struct {
+		name          string
+		image         *ec2.DescribeImagesOutput
+		describeErr   error
+		expectedError string
+	}{
+		{
+			name:  "HVM AMI",
+			image: image(ec2.VirtualizationTypeHvm),
+		},
+		{
+			name:          "Paravirtual AMI",
+			image:         image(ec2.VirtualizationTypeParavirtual),
+			expectedError: "AMI ami-1 uses paravirtual virtualization, only hvm AMIs are supported",
+		},
+		{
+			name:          "AMI not found",
+			image:         &ec2.DescribeImagesOutput{},
+			expectedError: "AMI ami-1 not found",
+		},
+		{
+			name:          "Describe images error",
+			describeErr:   fmt.Errorf("error"),
+			expectedError: "error describing AMI ami-1: error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-1"})}).Return(tc.image, tc.describeErr)
+
+			err := validateAMIVirtualization(mockAWSClient, "ami-1")
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateBlockDeviceNames(t *testing.T) {
+	rootVolume := machinev1.BlockDeviceMappingSpec{EBS: &machinev1.EBSBlockDeviceSpec{VolumeSize: aws.Int64(120)}}
+	dataVolume := func(deviceName string) machinev1.BlockDeviceMappingSpec {
+		return machinev1.BlockDeviceMappingSpec{
+			DeviceName: aws.String(deviceName),
+			EBS:        &machinev1.EBSBlockDeviceSpec{VolumeSize: aws.Int64(50)},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		mappings      []machinev1.BlockDeviceMappingSpec
+		image         *ec2.DescribeImagesOutput
+		expectedError string
+	}{
+		{
+			name:     "Root and data volumes",
+			mappings: []machinev1.BlockDeviceMappingSpec{rootVolume, dataVolume("/dev/xvdb"), dataVolume("/dev/xvdc")},
+		},
+		{
+			name:     "Data volumes only",
+			mappings: []machinev1.BlockDeviceMappingSpec{dataVolume("/dev/xvdb")},
+		},
+		{
+			name:          "Data volume on the root device",
+			mappings:      []machinev1.BlockDeviceMappingSpec{rootVolume, dataVolume("/dev/xvda")},
+			expectedError: "block device /dev/xvda is the root device of AMI ami-1, omit the device name to configure the root volume",
+		},
+		{
+			name:          "Data volumes on the same device",
+			mappings:      []machinev1.BlockDeviceMappingSpec{dataVolume("/dev/xvdb"), dataVolume("/dev/xvdb")},
+			expectedError: "block device /dev/xvdb is mapped more than once",
+		},
+		{
+			name:          "Several root volumes",
+			mappings:      []machinev1.BlockDeviceMappingSpec{rootVolume, rootVolume},
+			expectedError: "block device /dev/xvda is mapped more than once",
+		},
+		{
+			name:          "AMI not found",
+			mappings:      []machinev1.BlockDeviceMappingSpec{rootVolume},
+			image:         &ec2.DescribeImagesOutput{},
+			expectedError: "AMI ami-1 not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			image := tc.image
+			if image == nil {
+				image = &ec2.DescribeImagesOutput{Images: []*ec2.Image{{ImageId: aws.String("ami-1"), RootDeviceName: aws.String("/dev/xvda")}}}
+			}
+			mockAWSClient.EXPECT().DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-1"})}).Return(image, nil)
+
+			err := validateBlockDeviceNames(mockAWSClient, "ami-1", tc.mappings)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateSubnetFreeIPs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		available     int64
+		needed        int
+		expectedError string
+	}{
+		{
+			name:      "Sufficient free addresses",
+			available: 10,
+			needed:    2,
+		},
+		{
+			name:      "Exactly enough free addresses",
+			available: 2,
+			needed:    2,
+		},
+		{
+			name:          "Insufficient free addresses",
+			available:     1,
+			needed:        2,
+			expectedError: "subnet \"subnet-1\" has 1 free IP addresses, 2 needed",
+		},
+		{
+			name:          "No free addresses",
+			needed:        1,
+			expectedError: "subnet \"subnet-1\" has 0 free IP addresses, 1 needed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-1"})}).
+				Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{
+					SubnetId:                aws.String("subnet-1"),
+					AvailableIpAddressCount: aws.Int64(tc.available),
+				}}}, nil)
+
+			err := validateSubnetFreeIPs(mockAWSClient, "subnet-1", tc.needed)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidatePublicIPv4Pool(t *testing.T) {
+	pool := func(available, total int64) *ec2.DescribePublicIpv4PoolsOutput {
+		return &ec2.DescribePublicIpv4PoolsOutput{PublicIpv4Pools: []*ec2.PublicIpv4Pool{{
+			PoolId:                     aws.String("ipv4pool-ec2-1"),
+			TotalAddressCount:          aws.Int64(total),
+			TotalAvailableAddressCount: aws.Int64(available),
+		}}}
+	}
+
+	testCases := []struct {
+		name          string
+		output        *ec2.DescribePublicIpv4PoolsOutput
+		err           error
+		expectedError string
+	}{
+		{
+			name:   "Pool with available addresses",
+			output: pool(12, 256),
+		},
+		{
+			name:          "Exhausted pool",
+			output:        pool(0, 256),
+			expectedError: "public IPv4 pool \"ipv4pool-ec2-1\" has no available addresses, 0 of 256 remaining",
+		},
+		{
+			name:          "Pool not found",
+			output:        &ec2.DescribePublicIpv4PoolsOutput{},
+			expectedError: "public IPv4 pool \"ipv4pool-ec2-1\" not found",
+		},
+		{
+			name:          "Error describing pool",
+			err:           errors.New("access denied"),
+			expectedError: "error describing public IPv4 pool \"ipv4pool-ec2-1\": access denied",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribePublicIpv4Pools(&ec2.DescribePublicIpv4PoolsInput{
+				PoolIds: aws.StringSlice([]string{"ipv4pool-ec2-1"}),
+			}).Return(tc.output, tc.err)
+
+			err := validatePublicIPv4Pool(mockAWSClient, "ipv4pool-ec2-1")
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateSubnetTGWAttachment(t *testing.T) {
+	attachment := func(state string) *ec2.TransitGatewayVpcAttachment {
+		return &ec2.TransitGatewayVpcAttachment{
+			TransitGatewayId: aws.String("tgw-1"),
+			VpcId:            aws.String("vpc-1"),
+			State:            aws.String(state),
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		tgwID         string
+		attachments   []*ec2.TransitGatewayVpcAttachment
+		expectedError string
+	}{
+		{
+			name: "No transit gateway",
+		},
+		{
+			name:        "VPC attached to the transit gateway",
+			tgwID:       "tgw-1",
+			attachments: []*ec2.TransitGatewayVpcAttachment{attachment(ec2.TransitGatewayAttachmentStateDeleted), attachment(ec2.TransitGatewayAttachmentStateAvailable)},
+		},
+		{
+			name:          "Attachment not available",
+			tgwID:         "tgw-1",
+			attachments:   []*ec2.TransitGatewayVpcAttachment{attachment(ec2.TransitGatewayAttachmentStatePendingAcceptance)},
+			expectedError: "VPC \"vpc-1\" of subnet \"subnet-1\" has no available attachment to transit gateway \"tgw-1\"",
+		},
+		{
+			name:          "VPC not attached",
+			tgwID:         "tgw-1",
+			expectedError: "VPC \"vpc-1\" of subnet \"subnet-1\" has no available attachment to transit gateway \"tgw-1\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.tgwID != "" {
+				mockAWSClient.EXPECT().DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-1"})}).
+					Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-1"), VpcId: aws.String("vpc-1")}}}, nil)
+				mockAWSClient.EXPECT().DescribeTransitGatewayVpcAttachments(&ec2.DescribeTransitGatewayVpcAttachmentsInput{
+					Filters: []*ec2.Filter{
+						{Name: aws.String("transit-gateway-id"), Values: aws.StringSlice([]string{tc.tgwID})},
+						{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{"vpc-1"})},
+					},
+				}).Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{TransitGatewayVpcAttachments: tc.attachments}, nil)
+			}
+
+			err := validateSubnetTGWAttachment(mockAWSClient, "subnet-1", tc.tgwID)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateSubnetSGSameVPC(t *testing.T) {
+	securityGroup := func(id, vpcID string) *ec2.SecurityGroup {
+		return &ec2.SecurityGroup{GroupId: aws.String(id), VpcId: aws.String(vpcID)}
+	}
+
+	testCases := []struct {
+		name           string
+		sgIDs          []string
+		securityGroups []*ec2.SecurityGroup
+		expectedError  string
+	}{
+		{
+			name: "No security groups",
+		},
+		{
+			name:           "Security groups in the VPC of the subnet",
+			sgIDs:          []string{"sg-1", "sg-2"},
+			securityGroups: []*ec2.SecurityGroup{securityGroup("sg-1", "vpc-1"), securityGroup("sg-2", "vpc-1")},
+		},
+		{
+			name:           "Security groups in another VPC",
+			sgIDs:          []string{"sg-1", "sg-2", "sg-3"},
+			securityGroups: []*ec2.SecurityGroup{securityGroup("sg-3", "vpc-3"), securityGroup("sg-1", "vpc-1"), securityGroup("sg-2", "vpc-2")},
+			expectedError:  "security groups sg-2 (vpc-2), sg-3 (vpc-3) are not in VPC \"vpc-1\" of subnet \"subnet-1\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if len(tc.sgIDs) > 0 {
+				mockAWSClient.EXPECT().DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-1"})}).
+					Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-1"), VpcId: aws.String("vpc-1")}}}, nil)
+				mockAWSClient.EXPECT().DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: aws.StringSlice(tc.sgIDs)}).
+					Return(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: tc.securityGroups}, nil)
+			}
+
+			err := validateSubnetSGSameVPC(mockAWSClient, "subnet-1", tc.sgIDs)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidatePartitionCapacity(t *testing.T) {
+	reservation := func(instances int) *ec2.Reservation {
+		reservation := &ec2.Reservation{}
+		for i := 0; i < instances; i++ {
+			reservation.Instances = append(reservation.Instances, &ec2.Instance{InstanceId: aws.String(fmt.Sprintf("i-%d", i))})
+		}
+		return reservation
+	}
+	input := func(nextToken *string) *ec2.DescribeInstancesInput {
+		return &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("placement-group-name"), Values: aws.StringSlice([]string{"group"})},
+				{Name: aws.String("placement-partition-number"), Values: aws.StringSlice([]string{"2"})},
+				{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"})},
+			},
+			NextToken: nextToken,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		maxInstances  int
+		expectations  func(*mockaws.MockClientMockRecorder)
+		expectedError string
+	}{
+		{
+			name:         "Partition with room",
+			maxInstances: 100,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeInstances(input(nil)).Return(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation(3), reservation(2)}}, nil)
+			},
+		},
+		{
+			name:         "Partition close to the limit",
+			maxInstances: 100,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeInstances(input(nil)).Return(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation(95)}}, nil)
+			},
+		},
+		{
+			name:         "Full partition",
+			maxInstances: 100,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeInstances(input(nil)).Return(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation(60)}, NextToken: aws.String("token")}, nil)
+				m.DescribeInstances(input(aws.String("token"))).Return(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation(40)}}, nil)
+			},
+			expectedError: "partition 2 of placement group \"group\" already has 100 instances, the limit is 100",
+		},
+		{
+			name:         "Cap chosen by the caller",
+			maxInstances: 10,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeInstances(input(nil)).Return(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation(10)}}, nil)
+			},
+			expectedError: "partition 2 of placement group \"group\" already has 10 instances, the limit is 10",
+		},
+		{
+			name:         "No cap",
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			err := validatePartitionCapacity(mockAWSClient, "group", 2, tc.maxInstances)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateZoneOptIn(t *testing.T) {
+	zone := func(name, groupName, optInStatus string) *ec2.DescribeAvailabilityZonesOutput {
+		return &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{{
+			ZoneName:    aws.String(name),
+			GroupName:   aws.String(groupName),
+			OptInStatus: aws.String(optInStatus),
+		}}}
+	}
+
+	testCases := []struct {
+		name          string
+		az            string
+		output        *ec2.DescribeAvailabilityZonesOutput
+		expectedError string
+	}{
+		{
+			name:   "Availability zone without opt-in",
+			az:     "us-east-1a",
+			output: zone("us-east-1a", "us-east-1", ec2.AvailabilityZoneOptInStatusOptInNotRequired),
+		},
+		{
+			name:   "Opted-in Local Zone",
+			az:     "us-east-1-bos-1a",
+			output: zone("us-east-1-bos-1a", "us-east-1-bos-1", ec2.AvailabilityZoneOptInStatusOptedIn),
+		},
+		{
+			name:          "Not opted-in Wavelength Zone",
+			az:            "us-east-1-wl1-bos-wlz-1",
+			output:        zone("us-east-1-wl1-bos-wlz-1", "us-east-1-wl1", ec2.AvailabilityZoneOptInStatusNotOptedIn),
+			expectedError: "availability zone \"us-east-1-wl1-bos-wlz-1\" requires the account to opt in to zone group \"us-east-1-wl1\"",
+		},
+		{
+			name:          "Unknown zone",
+			az:            "us-east-1z",
+			output:        &ec2.DescribeAvailabilityZonesOutput{},
+			expectedError: "availability zone \"us-east-1z\" not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{
+				AllAvailabilityZones: aws.Bool(true),
+				ZoneNames:            aws.StringSlice([]string{tc.az}),
+			}).Return(tc.output, nil)
+
+			err := validateZoneOptIn(mockAWSClient, tc.az)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateAcceleratorAZ(t *testing.T) {
+	testCases := []struct {
+		name           string
+		instanceType   string
+		expectDescribe bool
+		offerings      []*ec2.InstanceTypeOffering
+		expectedError  string
+	}{
+		{
+			name:           "Accelerated instance type offered in the zone",
+			instanceType:   "p3.2xlarge",
+			expectDescribe: true,
+			offerings: []*ec2.InstanceTypeOffering{{
+				InstanceType: aws.String("p3.2xlarge"),
+				LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+				Location:     aws.String("us-east-1a"),
+			}},
+		},
+		{
+			name:           "Accelerated instance type not offered in the zone",
+			instanceType:   "p3.2xlarge",
+			expectDescribe: true,
+			expectedError:  "accelerated instance type \"p3.2xlarge\" cannot be launched in availability zone \"us-east-1a\": instance type \"p3.2xlarge\" is not offered in availability-zone \"us-east-1a\"",
+		},
+		{
+			name:          "Instance type without accelerators",
+			instanceType:  "m5.large",
+			expectedError: "instance type \"m5.large\" does not provide accelerators",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			if tc.expectDescribe {
+				mockAWSClient.EXPECT().DescribeInstanceTypeOfferings(&ec2.DescribeInstanceTypeOfferingsInput{
+					LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+					Filters: []*ec2.Filter{
+						{Name: aws.String("instance-type"), Values: aws.StringSlice([]string{tc.instanceType})},
+						{Name: aws.String("location"), Values: aws.StringSlice([]string{"us-east-1a"})},
+					},
+				}).Return(&ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: tc.offerings}, nil)
+			}
+
+			err := validateAcceleratorAZ(mockAWSClient, tc.instanceType, "us-east-1a")
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expectedError) {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateEIPBorderGroup(t *testing.T) {
+	testCases := []struct {
+		name            string
+		az              string
+		zoneBorderGroup string
+		expectedError   string
+	}{
+		{
+			name:            "Availability zone in the border group of the Elastic IP",
+			az:              "us-east-1a",
+			zoneBorderGroup: "us-east-1",
+		},
+		{
+			name:            "Local Zone in another border group",
+			az:              "us-east-1-bos-1a",
+			zoneBorderGroup: "us-east-1-bos-1",
+			expectedError:   "Elastic IP \"eipalloc-1\" belongs to network border group \"us-east-1\", availability zone \"us-east-1-bos-1a\" belongs to network border group \"us-east-1-bos-1\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			mockAWSClient.EXPECT().DescribeAddresses(&ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-1"})}).
+				Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{{
+					AllocationId:       aws.String("eipalloc-1"),
+					NetworkBorderGroup: aws.String("us-east-1"),
+				}}}, nil)
+			mockAWSClient.EXPECT().DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{ZoneNames: aws.StringSlice([]string{tc.az})}).
+				Return(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{{
+					ZoneName:           aws.String(tc.az),
+					NetworkBorderGroup: aws.String(tc.zoneBorderGroup),
+				}}}, nil)
+
+			err := validateEIPBorderGroup(mockAWSClient, "eipalloc-1", tc.az)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Unexpected error output: expected '%s', got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package machine
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awsclient "github.com/openshift/machine-api-provider-aws/pkg/client"
+	"k8s.io/klog/v2"
+)
+
+// VolumeInfo describes an EBS volume attached to the instance of a machine.
+type VolumeInfo struct {
+	// VolumeID is the ID of the volume
+	VolumeID string
+	// DeviceName is the device name the volume is attached at, e.g. /dev/xvda
+	DeviceName string
+	// Size is the size of the volume in GiB
+	Size int64
+	// VolumeType is the EBS volume type, e.g. gp3
+	VolumeType string
+	// Iops is the provisioned IOPS of the volume, zero when not applicable
+	Iops int64
+	// Throughput is the provisioned throughput of the volume in MiB/s, zero when not applicable
+	Throughput int64
+	// Encrypted indicates whether the volume is encrypted
+	Encrypted bool
+	// KMSKeyID is the ARN of the KMS key used to encrypt the volume
+	KMSKeyID string
+}
+
+// machineVolumes describes the EBS volumes attached to the instance, in the order of its block device mappings.
+func machineVolumes(client awsclient.Client, instance *ec2.Instance) ([]VolumeInfo, error) {
+	if instance == nil || instance.InstanceId == nil {
+		return nil, fmt.Errorf("unexpected nil found in instance: %v", instance)
+	}
+
+	volumeIDs := []*string{}
+	deviceNames := map[string]string{}
+	for _, blockDeviceMapping := range instance.BlockDeviceMappings {
+		if blockDeviceMapping.Ebs != nil && blockDeviceMapping.Ebs.VolumeId != nil {
+			volumeIDs = append(volumeIDs, blockDeviceMapping.Ebs.VolumeId)
+			deviceNames[*blockDeviceMapping.Ebs.VolumeId] = aws.StringValue(blockDeviceMapping.DeviceName)
+		}
+	}
+	if len(volumeIDs) == 0 {
+		return []VolumeInfo{}, nil
+	}
+
+	result, err := client.DescribeVolumes(&ec2.DescribeVolumesInput{VolumeIds: volumeIDs})
+	if err != nil {
+		klog.Errorf("Error describing volumes of instance %s: %v", *instance.InstanceId, err)
+		return nil, fmt.Errorf("error describing volumes of instance %s: %v", *instance.InstanceId, err)
+	}
+
+	volumes := make(map[string]*ec2.Volume, len(result.Volumes))
+	for _, volume := range result.Volumes {
+		volumes[aws.StringValue(volume.VolumeId)] = volume
+	}
+
+	volumeInfos := make([]VolumeInfo, 0, len(volumeIDs))
+	for _, volumeID := range aws.StringValueSlice(volumeIDs) {
+		volume, ok := volumes[volumeID]
+		if !ok {
+			return nil, fmt.Errorf("volume %s of instance %s not found", volumeID, *instance.InstanceId)
+		}
+		volumeInfos = append(volumeInfos, VolumeInfo{
+			VolumeID:   volumeID,
+			DeviceName: deviceNames[volumeID],
+			Size:       aws.Int64Value(volume.Size),
+			VolumeType: aws.StringValue(volume.VolumeType),
+			Iops:       aws.Int64Value(volume.Iops),
+			Throughput: aws.Int64Value(volume.Throughput),
+			Encrypted:  aws.BoolValue(volume.Encrypted),
+			KMSKeyID:   aws.StringValue(volume.KmsKeyId),
+		})
+	}
+
+	return volumeInfos, nil
+}
@@ -0,0 +1,103 @@
+package machine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	mockaws "github.com/openshift/machine-api-provider-aws/pkg/client/mock"
+)
+
+func TestMachineVolumes(t *testing.T) {
+	instance := &ec2.Instance{
+		InstanceId: aws.String(stubInstanceID),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+			{DeviceName: aws.String("/dev/xvdb"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+		},
+	}
+	describeInput := &ec2.DescribeVolumesInput{VolumeIds: aws.StringSlice([]string{"vol-root", "vol-data"})}
+
+	testCases := []struct {
+		name          string
+		instance      *ec2.Instance
+		expectations  func(*mockaws.MockClientMockRecorder)
+		expected      []VolumeInfo
+		expectedError bool
+	}{
+		{
+			name:     "Volumes of differing types",
+			instance: instance,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeVolumes(describeInput).Return(&ec2.DescribeVolumesOutput{
+					Volumes: []*ec2.Volume{
+						{
+							VolumeId:   aws.String("vol-data"),
+							Size:       aws.Int64(500),
+							VolumeType: aws.String(ec2.VolumeTypeIo1),
+							Iops:       aws.Int64(4000),
+							Encrypted:  aws.Bool(true),
+							KmsKeyId:   aws.String("arn:aws:kms:us-east-1:123456789012:key/abcd"),
+						},
+						{
+							VolumeId:   aws.String("vol-root"),
+							Size:       aws.Int64(120),
+							VolumeType: aws.String(ec2.VolumeTypeGp3),
+							Iops:       aws.Int64(3000),
+							Throughput: aws.Int64(125),
+						},
+					},
+				}, nil)
+			},
+			expected: []VolumeInfo{
+				{VolumeID: "vol-root", DeviceName: "/dev/xvda", Size: 120, VolumeType: ec2.VolumeTypeGp3, Iops: 3000, Throughput: 125},
+				{VolumeID: "vol-data", DeviceName: "/dev/xvdb", Size: 500, VolumeType: ec2.VolumeTypeIo1, Iops: 4000, Encrypted: true,
+					KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/abcd"},
+			},
+		},
+		{
+			name:         "Instance without volumes",
+			instance:     &ec2.Instance{InstanceId: aws.String(stubInstanceID)},
+			expectations: func(m *mockaws.MockClientMockRecorder) {},
+			expected:     []VolumeInfo{},
+		},
+		{
+			name:     "Volume missing from the response",
+			instance: instance,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeVolumes(describeInput).Return(&ec2.DescribeVolumesOutput{
+					Volumes: []*ec2.Volume{{VolumeId: aws.String("vol-root")}},
+				}, nil)
+			},
+			expectedError: true,
+		},
+		{
+			name:     "Describe error",
+			instance: instance,
+			expectations: func(m *mockaws.MockClientMockRecorder) {
+				m.DescribeVolumes(describeInput).Return(nil, fmt.Errorf("error"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockAWSClient := mockaws.NewMockClient(mockCtrl)
+			tc.expectations(mockAWSClient.EXPECT())
+
+			volumes, err := machineVolumes(mockAWSClient, tc.instance)
+			if tc.expectedError != (err != nil) {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+			if !tc.expectedError && !reflect.DeepEqual(volumes, tc.expected) {
+				t.Errorf("Expected volumes %+v, got %+v", tc.expected, volumes)
+			}
+		})
+	}
+}